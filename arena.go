@@ -0,0 +1,54 @@
+package memguard
+
+import (
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+Arena is a fixed-size region of locked memory from which LockedBuffers can be allocated. It is useful for programs that must never lock more than a set amount of memory, since the whole region is locked up front and allocations from it do not lock any more.
+
+Every LockedBuffer allocated from an Arena has its own guard pages and canary, so it is protected exactly as one returned by NewBuffer. As a result each allocation consumes two pages for its guards in addition to the pages holding its data, rounded up to a multiple of the system page size.
+
+The memory is only released when Destroy is called, so an Arena should be destroyed once it is no longer needed.
+*/
+type Arena struct {
+	arena *core.Arena
+}
+
+/*
+NewArena allocates and locks an Arena of totalBytes bytes, rounded up to a multiple of the system page size. An error is returned if the memory cannot be allocated or locked.
+*/
+func NewArena(totalBytes int) (*Arena, error) {
+	a, err := core.NewArena(totalBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &Arena{a}, nil
+}
+
+/*
+New allocates a mutable LockedBuffer of a given length from an Arena. Destroying the LockedBuffer returns its memory to the Arena.
+
+If the Arena does not have enough adjacent free pages to hold the LockedBuffer, core.ErrArenaFull is returned. core.ErrArenaExpired is returned if the Arena has been destroyed. In either case a null buffer is returned.
+*/
+func (a *Arena) New(length int) (*LockedBuffer, error) {
+	buf, err := a.arena.NewBuffer(length)
+	if err != nil {
+		return newNullBuffer(), err
+	}
+	return newBuffer(buf), nil
+}
+
+/*
+Free returns the number of bytes of an Arena that are not being used by any LockedBuffer, including those that would be needed for guard pages.
+*/
+func (a *Arena) Free() int {
+	return a.arena.Free()
+}
+
+/*
+Destroy destroys all of the LockedBuffers allocated from an Arena, and then wipes, unlocks, and frees its memory. The Arena cannot be used afterwards.
+*/
+func (a *Arena) Destroy() {
+	a.arena.Destroy()
+}
@@ -0,0 +1,75 @@
+package memguard
+
+import (
+	"os"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestArena(t *testing.T) {
+	pageSize := os.Getpagesize()
+	a, err := NewArena(16 * pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	if a.Free() != 16*pageSize {
+		t.Error("unexpected free space", a.Free())
+	}
+	peak := PeakLockedBytes()
+
+	// Allocate until full; each buffer uses four pages.
+	var bufs []*LockedBuffer
+	for {
+		b, err := a.New(2 * pageSize)
+		if err == core.ErrArenaFull {
+			if b.IsAlive() {
+				t.Error("expected null buffer")
+			}
+			break
+		}
+		if err != nil {
+			t.Error(err)
+			break
+		}
+		bufs = append(bufs, b)
+	}
+	if len(bufs) != 4 || a.Free() != 0 {
+		t.Error("unexpected number of buffers", len(bufs), "or free space", a.Free())
+	}
+	if PeakLockedBytes() != peak {
+		t.Error("arena allocations increased locked memory")
+	}
+
+	// Buffers are independent of each other.
+	for i, b := range bufs {
+		if !b.IsMutable() || b.Size() != 2*pageSize {
+			t.Error("unexpected buffer state")
+		}
+		b.Bytes()[0] = byte(i)
+	}
+	for i, b := range bufs {
+		if b.Bytes()[0] != byte(i) {
+			t.Error("buffers share memory")
+		}
+	}
+
+	// Freed memory can be reused.
+	bufs[1].Destroy()
+	if a.Free() != 4*pageSize {
+		t.Error("unexpected free space", a.Free())
+	}
+	b, err := a.New(pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Destroying the arena destroys its buffers.
+	a.Destroy()
+	if b.IsAlive() || bufs[0].IsAlive() || bufs[3].IsAlive() {
+		t.Error("buffers not destroyed")
+	}
+	if _, err := a.New(32); err != core.ErrArenaExpired {
+		t.Error("expected ErrArenaExpired; got", err)
+	}
+}
@@ -0,0 +1,92 @@
+package memguard
+
+import (
+	"os"
+	"sync"
+
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+Array is a fixed number of equally sized slots of guarded memory, allocated and locked together as one Arena, for stores that hold a bounded number of credentials and reuse their slots. Each slot is a LockedBuffer with its own guard pages and canary, so writing past the end of one slot faults instead of reaching its neighbours, and a slot can be wiped and rewritten any number of times without any memory being allocated or locked.
+
+An Array is safe for concurrent use. It should be destroyed with Destroy once it is no longer needed.
+*/
+type Array struct {
+	sync.Mutex
+	arena *Arena
+	slots []*LockedBuffer
+	size  int
+}
+
+/*
+NewArray allocates an Array of count slots of slotSize bytes each. Every slot starts out mutable and filled with zeros. Each slot occupies its data rounded up to a multiple of the system page size, plus two guard pages.
+
+core.ErrNullBuffer is returned if either argument is less than one. An error is also returned if the memory cannot be allocated or locked.
+*/
+func NewArray(count, slotSize int) (*Array, error) {
+	if count < 1 || slotSize < 1 {
+		return nil, core.ErrNullBuffer
+	}
+
+	page := os.Getpagesize()
+	arena, err := NewArena(count * (2*page + (slotSize+page-1)/page*page))
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Array{arena: arena, slots: make([]*LockedBuffer, count), size: slotSize}
+	for i := range a.slots {
+		if a.slots[i], err = arena.New(slotSize); err != nil {
+			arena.Destroy()
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+/*
+Slot returns the LockedBuffer holding slot i of an Array. The same LockedBuffer is returned every time, so changes made through it persist in the slot. Clearing it makes the slot available for reuse. If the slot has been destroyed, it is replaced by a fresh zeroed one in the same memory.
+
+core.ErrOutOfBounds is returned if i is not the index of a slot, and core.ErrBufferExpired is returned if the Array has been destroyed. A null buffer is returned in these cases.
+*/
+func (a *Array) Slot(i int) (*LockedBuffer, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.slots == nil {
+		return newNullBuffer(), core.ErrBufferExpired
+	}
+	if i < 0 || i >= len(a.slots) {
+		return newNullBuffer(), core.ErrOutOfBounds
+	}
+	if !a.slots[i].IsAlive() {
+		b, err := a.arena.New(a.size)
+		if err != nil {
+			return newNullBuffer(), err
+		}
+		a.slots[i] = b
+	}
+	return a.slots[i], nil
+}
+
+// Len returns the number of slots of an Array, or zero if it has been destroyed.
+func (a *Array) Len() int {
+	a.Lock()
+	defer a.Unlock()
+	return len(a.slots)
+}
+
+/*
+Destroy wipes and destroys every slot of an Array and then wipes, unlocks and frees its memory. The Array and its slots cannot be used afterwards.
+*/
+func (a *Array) Destroy() {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.slots == nil {
+		return
+	}
+	a.arena.Destroy()
+	a.slots = nil
+}
@@ -0,0 +1,105 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestArray(t *testing.T) {
+	a, err := NewArray(4, 32)
+	if err != nil {
+		t.Error(err)
+	}
+	if a.Len() != 4 {
+		t.Error("unexpected length", a.Len())
+	}
+	peak := PeakLockedBytes()
+
+	// Slots start out zeroed and mutable, and keep their contents.
+	for i := 0; i < a.Len(); i++ {
+		s, err := a.Slot(i)
+		if err != nil {
+			t.Error(err)
+		}
+		if s.Size() != 32 || !s.IsMutable() || !equalTo(s, make([]byte, 32)) {
+			t.Error("unexpected slot state", i)
+		}
+		s.Copy(bytes.Repeat([]byte{byte(i + 1)}, 32))
+	}
+	for i := 0; i < a.Len(); i++ {
+		s, _ := a.Slot(i)
+		if !equalTo(s, bytes.Repeat([]byte{byte(i + 1)}, 32)) {
+			t.Error("slot contents not kept or slots overlap", i)
+		}
+	}
+
+	// Slots are wiped and rewritten individually, without allocating.
+	s, _ := a.Slot(1)
+	if err := s.Clear(); err != nil {
+		t.Error(err)
+	}
+	s.Copy([]byte("yellow submarine"))
+	if again, _ := a.Slot(1); again != s {
+		t.Error("slot was reallocated")
+	}
+	for i, expected := range [][]byte{
+		bytes.Repeat([]byte{1}, 32),
+		append([]byte("yellow submarine"), make([]byte, 16)...),
+		bytes.Repeat([]byte{3}, 32),
+		bytes.Repeat([]byte{4}, 32),
+	} {
+		if s, _ := a.Slot(i); !equalTo(s, expected) {
+			t.Error("unexpected contents of slot", i, s.Bytes())
+		}
+	}
+	if PeakLockedBytes() != peak {
+		t.Error("slot reuse increased locked memory")
+	}
+
+	// Writing past the end of a slot faults rather than reaching its neighbour.
+	s, _ = a.Slot(2)
+	end := (*byte)(unsafe.Pointer(uintptr(unsafe.Pointer(&s.Bytes()[31])) + 1))
+	if !faults(func() { *end = 0xff }) {
+		t.Error("expected fault writing past the end of a slot")
+	}
+
+	// Destroyed slots are replaced.
+	s.Destroy()
+	r, err := a.Slot(2)
+	if err != nil {
+		t.Error(err)
+	}
+	if r == s || !r.IsAlive() || !equalTo(r, make([]byte, 32)) {
+		t.Error("destroyed slot not replaced")
+	}
+
+	// Out of range slots.
+	for _, i := range []int{-1, 4} {
+		if b, err := a.Slot(i); err != core.ErrOutOfBounds || b.IsAlive() {
+			t.Error("expected ErrOutOfBounds; got", err, i)
+		}
+	}
+
+	// Destroying the array destroys its slots.
+	a.Destroy()
+	if r.IsAlive() {
+		t.Error("slot not destroyed")
+	}
+	if _, err := a.Slot(0); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if a.Len() != 0 {
+		t.Error("unexpected length", a.Len())
+	}
+	a.Destroy()
+
+	// Invalid sizes.
+	for _, args := range [][2]int{{0, 32}, {4, 0}, {-1, 1}} {
+		if _, err := NewArray(args[0], args[1]); err != core.ErrNullBuffer {
+			t.Error("expected ErrNullBuffer; got", err, args)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package memguard
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+// ErrInvalidPublicKey is returned when a recipient's public key is not a usable X25519 public key.
+var ErrInvalidPublicKey = errors.New("<memguard::ErrInvalidPublicKey> public key must be 32 bytes and not of low order")
+
+// Number of bytes that ExportEncrypted adds to the data: the ephemeral public key and the 16 byte authentication tag.
+const boxOverhead = curve25519.PointSize + 16
+
+/*
+NewTransportKeyPair generates an X25519 key pair for receiving secrets sent with ExportEncrypted. The private key is generated directly inside an immutable LockedBuffer, and is passed to ImportEncrypted. The public key may be shared freely.
+*/
+func NewTransportKeyPair() (publicKey []byte, privateKey *LockedBuffer, err error) {
+	var pub [curve25519.PointSize]byte
+	priv := NewBuffer(curve25519.ScalarSize)
+	if err := priv.Access(true, func(scalar []byte) error {
+		if err := core.Scramble(scalar); err != nil {
+			return err
+		}
+		curve25519.ScalarBaseMult(&pub, (*[curve25519.ScalarSize]byte)(unsafe.Pointer(&scalar[0])))
+		return nil
+	}); err != nil {
+		priv.Destroy()
+		return nil, newNullBuffer(), err
+	}
+
+	priv.Freeze()
+	return pub[:], priv, nil
+}
+
+/*
+ExportEncrypted encrypts the contents of a LockedBuffer so that only the holder of the private key corresponding to a recipient's X25519 public key can decrypt it with ImportEncrypted, making it safe to send over a channel that is not trusted. This is a sealed box: a fresh ephemeral key pair is generated for every call and its public key is included in the result, so the sender cannot be identified and nothing needs to be agreed upon in advance. The data is encrypted with XChaCha20-Poly1305 under a key derived from the X25519 shared secret with HChaCha20, using a nonce derived from the two public keys with BLAKE2b.
+
+The ephemeral private key, the shared secret and the derived key are held in guarded memory and destroyed before returning, although the golang.org/x/crypto package holds intermediate values on the stack for the duration of the call. The LockedBuffer is left unchanged.
+
+ErrInvalidPublicKey is returned if the public key is not 32 bytes long or is of low order. An error is also returned if the LockedBuffer has been destroyed.
+*/
+func ExportEncrypted(b *LockedBuffer, recipientPublicKey []byte) ([]byte, error) {
+	if len(recipientPublicKey) != curve25519.PointSize {
+		return nil, ErrInvalidPublicKey
+	}
+	var recipient, ephemeral [curve25519.PointSize]byte
+	copy(recipient[:], recipientPublicKey)
+
+	var blob []byte
+	err := withBoxKey(func(scalar, shared *[32]byte, key []byte) error {
+		if err := core.Scramble(scalar[:]); err != nil {
+			return err
+		}
+		curve25519.ScalarBaseMult(&ephemeral, scalar)
+		if !deriveBoxKey(key, shared, scalar, &recipient) {
+			return ErrInvalidPublicKey
+		}
+
+		aead, err := chacha20poly1305.NewX(key)
+		if err != nil {
+			return err
+		}
+		return b.Access(false, func(data []byte) error {
+			blob = make([]byte, 0, boxOverhead+len(data))
+			blob = append(blob, ephemeral[:]...)
+			blob = aead.Seal(blob, boxNonce(&ephemeral, &recipient), data, nil)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+/*
+ImportEncrypted decrypts data produced by ExportEncrypted directly into a new immutable LockedBuffer, using the recipient's X25519 private key held in a LockedBuffer, such as one created by NewTransportKeyPair. The private key is used directly from guarded memory, and the shared secret and derived key are held in guarded memory and destroyed before returning.
+
+If the private key is incorrect or the data has been modified, core.ErrDecryptionFailed is returned along with a null buffer. core.ErrInvalidKeyLength is returned if the private key is not 32 bytes long.
+*/
+func ImportEncrypted(blob []byte, recipientPrivateKey *LockedBuffer) (*LockedBuffer, error) {
+	if len(blob) < boxOverhead {
+		return newNullBuffer(), core.ErrDecryptionFailed
+	}
+	var recipient, ephemeral [curve25519.PointSize]byte
+	copy(ephemeral[:], blob)
+	ciphertext := blob[curve25519.PointSize:]
+
+	b := newNullBuffer()
+	err := recipientPrivateKey.Access(false, func(priv []byte) error {
+		if len(priv) != curve25519.ScalarSize {
+			return core.ErrInvalidKeyLength
+		}
+		scalar := (*[curve25519.ScalarSize]byte)(unsafe.Pointer(&priv[0]))
+		curve25519.ScalarBaseMult(&recipient, scalar)
+
+		return withBoxKey(func(_, shared *[32]byte, key []byte) error {
+			if !deriveBoxKey(key, shared, scalar, &ephemeral) {
+				return core.ErrDecryptionFailed
+			}
+			aead, err := chacha20poly1305.NewX(key)
+			if err != nil {
+				return err
+			}
+
+			// Decrypt into guarded memory.
+			var out []byte
+			if size := len(ciphertext) - aead.Overhead(); size > 0 {
+				b = NewBuffer(size)
+				out = b.Bytes()
+			}
+			if _, err := aead.Open(out[:0], boxNonce(&ephemeral, &recipient), ciphertext, nil); err != nil {
+				b.Destroy()
+				return core.ErrDecryptionFailed
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return newNullBuffer(), err
+	}
+
+	b.Freeze()
+	return b, nil
+}
+
+// Calls a function with space in guarded memory for a private key, a shared secret and a derived key, which is destroyed afterwards.
+func withBoxKey(fn func(scalar, shared *[32]byte, key []byte) error) error {
+	work := NewBuffer(3 * 32)
+	defer work.Destroy()
+	return work.Access(true, func(w []byte) error {
+		return fn((*[32]byte)(unsafe.Pointer(&w[0])), (*[32]byte)(unsafe.Pointer(&w[32])), w[64:])
+	})
+}
+
+// Computes the X25519 shared secret of a private key and a public key, and derives an encryption key from it with HChaCha20. It reports false if the public key is of low order, in which case the shared secret is zero and does not depend on the private key.
+func deriveBoxKey(key []byte, shared, scalar, point *[32]byte) bool {
+	curve25519.ScalarMult(shared, scalar, point)
+	var acc byte
+	for _, v := range shared {
+		acc |= v
+	}
+	if acc == 0 {
+		return false
+	}
+
+	var zero [16]byte
+	k, err := chacha20.HChaCha20(shared[:], zero[:])
+	if err != nil {
+		return false
+	}
+	core.Move(key, k)
+	return true
+}
+
+// Derives the nonce for a sealed box from the ephemeral and recipient public keys. Each ephemeral key pair is used only once, so the nonce never repeats under the same key.
+func boxNonce(ephemeral, recipient *[curve25519.PointSize]byte) []byte {
+	h, _ := blake2b.New(chacha20poly1305.NonceSizeX, nil)
+	h.Write(ephemeral[:])
+	h.Write(recipient[:])
+	return h.Sum(nil)
+}
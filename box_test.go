@@ -0,0 +1,138 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestNewTransportKeyPair(t *testing.T) {
+	pub, priv, err := NewTransportKeyPair()
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	defer priv.Destroy()
+	if len(pub) != 32 || priv.Size() != 32 || priv.IsMutable() {
+		t.Error("unexpected key pair:", len(pub), priv.Size(), priv.IsMutable())
+	}
+
+	// The public key corresponds to the private key.
+	expected, err := curve25519.X25519(priv.Bytes(), curve25519.Basepoint)
+	if err != nil || !bytes.Equal(pub, expected) {
+		t.Error("public key does not match private key:", err)
+	}
+
+	pub2, priv2, _ := NewTransportKeyPair()
+	defer priv2.Destroy()
+	if bytes.Equal(pub, pub2) {
+		t.Error("key pairs are identical")
+	}
+}
+
+func TestExportEncrypted(t *testing.T) {
+	pub, priv, err := NewTransportKeyPair()
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	defer priv.Destroy()
+
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+
+	blob, err := ExportEncrypted(b, pub)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if len(blob) != b.Size()+boxOverhead || bytes.Contains(blob, b.Bytes()) {
+		t.Error("unexpected output", blob)
+	}
+	if !equalTo(b, []byte("yellow submarine")) {
+		t.Error("source was modified")
+	}
+
+	// The same data is encrypted differently each time.
+	blob2, err := ExportEncrypted(b, pub)
+	if err != nil || bytes.Equal(blob, blob2) {
+		t.Error("outputs are identical:", err)
+	}
+
+	// Round trip.
+	for _, data := range [][]byte{blob, blob2} {
+		out, err := ImportEncrypted(data, priv)
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if !equalTo(out, []byte("yellow submarine")) || out.IsMutable() {
+			t.Error("unexpected result", out.String())
+		}
+		out.Destroy()
+	}
+
+	// Invalid public keys are refused.
+	if _, err := ExportEncrypted(b, pub[:31]); err != ErrInvalidPublicKey {
+		t.Error("expected ErrInvalidPublicKey; got", err)
+	}
+	if _, err := ExportEncrypted(b, make([]byte, 32)); err != ErrInvalidPublicKey {
+		t.Error("expected ErrInvalidPublicKey; got", err)
+	}
+
+	// Destroyed buffers are refused.
+	d := NewBufferRandom(8)
+	d.Destroy()
+	if _, err := ExportEncrypted(d, pub); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestImportEncrypted(t *testing.T) {
+	pub, priv, _ := NewTransportKeyPair()
+	defer priv.Destroy()
+	b := NewBufferRandom(64)
+	defer b.Destroy()
+	blob, err := ExportEncrypted(b, pub)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+
+	// The wrong private key is refused.
+	_, wrong, _ := NewTransportKeyPair()
+	defer wrong.Destroy()
+	if out, err := ImportEncrypted(blob, wrong); err != core.ErrDecryptionFailed || out.Size() != 0 {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+
+	// Modifying any part of the data is detected.
+	for i := range blob {
+		blob[i] ^= 1
+		if _, err := ImportEncrypted(blob, priv); err != core.ErrDecryptionFailed {
+			t.Error("expected ErrDecryptionFailed for modified byte", i, "got", err)
+		}
+		blob[i] ^= 1
+	}
+	if _, err := ImportEncrypted(blob[:boxOverhead-1], priv); err != core.ErrDecryptionFailed {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+
+	// Invalid and destroyed private keys are refused.
+	short := NewBufferRandom(16)
+	defer short.Destroy()
+	if _, err := ImportEncrypted(blob, short); err != core.ErrInvalidKeyLength {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+	out, err := ImportEncrypted(blob, priv)
+	if err != nil || !equalTo(out, b.Bytes()) {
+		t.Error("unexpected result:", err)
+	}
+	out.Destroy()
+	priv.Destroy()
+	if _, err := ImportEncrypted(blob, priv); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+
+	// Null buffers have nothing to export.
+	if _, err := ExportEncrypted(NewBuffer(0), pub); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -2,18 +2,39 @@ package memguard
 
 import (
 	"bytes"
+	"crypto/subtle"
+	"errors"
 	"io"
+	"net"
 	"os"
+	"reflect"
 	"runtime"
+	"time"
 	"unsafe"
 
 	"github.com/awnumar/memguard/core"
 )
 
+// ErrInvalidCondition is returned when a constant-time operation is given a condition other than 0 or 1.
+var ErrInvalidCondition = errors.New("<memguard::ErrInvalidCondition> condition must be 0 or 1")
+
+// ErrExcessData is returned when a source holds more data than was expected.
+var ErrExcessData = errors.New("<memguard::ErrExcessData> source contains more data than expected")
+
+// ErrInvalidCopyPolicy is returned when CopyBufferPolicy is given a policy that it does not recognise.
+var ErrInvalidCopyPolicy = errors.New("<memguard::ErrInvalidCopyPolicy> unknown copy policy")
+
+// ErrSelfMove is returned when a LockedBuffer is moved into itself.
+var ErrSelfMove = errors.New("<memguard::ErrSelfMove> cannot move a LockedBuffer into itself")
+
 /*
 LockedBuffer is a structure that holds raw sensitive data.
 
 The number of LockedBuffers that you are able to create is limited by how much memory your system's kernel allows each process to mlock/VirtualLock. Therefore you should call Destroy on LockedBuffers that you no longer need or defer a Destroy call after creating a new LockedBuffer.
+
+A nil *LockedBuffer behaves like one that has been destroyed: queries such as Size and IsAlive return zero values and operations fail with core.ErrBufferExpired or do nothing, rather than panicking.
+
+A LockedBuffer only holds references to its state, so a copy of the structure refers to the same memory and sees the same state as the original. Destroying either one destroys both, after which the other behaves as destroyed rather than referring to freed memory, and repeated calls to Destroy do nothing. The memory is only finalized once every copy is unreachable.
 */
 type LockedBuffer struct {
 	*core.Buffer
@@ -43,18 +64,40 @@ func newNullBuffer() *LockedBuffer {
 NewBuffer creates a mutable data container of the specified size.
 */
 func NewBuffer(size int) *LockedBuffer {
-	// Construct a Buffer of the specified size.
-	buf, err := core.NewBuffer(size)
+	b, _ := NewWithOptions(size)
+	return b
+}
+
+/*
+NewBufferWithTimeout creates a mutable data container of the specified size, giving up with core.ErrLockTimeout if locking its memory does not complete within a given duration. This prevents a program from hanging at startup on systems where locking memory can be slow under memory pressure.
+
+The memory may have been allocated successfully even if locking it timed out, in which case it is freed in the background once the lock completes. A duration of zero or less has already expired, so core.ErrLockTimeout is returned without allocating anything. A size of less than one returns a null buffer.
+*/
+func NewBufferWithTimeout(size int, d time.Duration) (*LockedBuffer, error) {
+	buf, err := core.NewBufferWithTimeout(size, d)
 	if err != nil {
-		return newNullBuffer()
+		if err == core.ErrNullBuffer {
+			return newNullBuffer(), nil
+		}
+		return newNullBuffer(), err
 	}
+	return newBuffer(buf), nil
+}
 
-	// Construct and return the wrapped container object.
-	return newBuffer(buf)
+/*
+NewBufferOnNode creates a mutable data container of the specified size whose memory is bound to a given NUMA node. This can reduce access latency on multi-socket systems. It is only supported on Linux.
+
+If the memory cannot be bound to the node, a warning is logged (see SetLogger) and the container is allocated as if by NewBuffer.
+*/
+func NewBufferOnNode(size, node int) *LockedBuffer {
+	b, _ := NewWithOptions(size, WithNode(node))
+	return b
 }
 
 /*
 NewBufferFromBytes constructs an immutable buffer from a byte slice. The source buffer is wiped after the value has been copied over to the created container.
+
+The data is copied directly from the source into guarded memory a byte at a time, so no intermediate copy of it is made on the heap or left behind on the stack.
 */
 func NewBufferFromBytes(src []byte) *LockedBuffer {
 	// Construct a buffer of the correct size.
@@ -106,6 +149,47 @@ func NewBufferFromReader(r io.Reader, size int) (*LockedBuffer, error) {
 	return b, nil
 }
 
+/*
+NewBufferFromReaders reads exactly total bytes from a sequence of io.Reader objects, each read until EOF in turn, directly into an immutable LockedBuffer. This allows a secret to be assembled from several sources, such as a salt file and a key file, without first concatenating them in unguarded memory.
+
+io.ErrUnexpectedEOF is returned if the readers together hold fewer than total bytes, and ErrExcessData if they hold more. On any error the data that was read is destroyed and a null buffer is returned. The single byte read to check for excess data is wiped.
+*/
+func NewBufferFromReaders(total int, readers ...io.Reader) (*LockedBuffer, error) {
+	r := io.MultiReader(readers...)
+
+	// Fill the buffer from the readers in sequence.
+	b := NewBuffer(total)
+	if b.Size() != 0 {
+		if _, err := io.ReadFull(r, b.Bytes()); err != nil {
+			b.Destroy()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return newNullBuffer(), err
+		}
+	}
+
+	// Check that every reader has been exhausted.
+	var probe [1]byte
+	defer core.Wipe(probe[:])
+	for {
+		n, err := r.Read(probe[:])
+		if n != 0 {
+			err = ErrExcessData
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			b.Destroy()
+			return newNullBuffer(), err
+		}
+	}
+
+	b.Freeze()
+	return b, nil
+}
+
 /*
 NewBufferFromReaderUntil constructs an immutable buffer containing data sourced from an io.Reader object.
 
@@ -166,217 +250,1284 @@ func NewBufferFromReaderUntil(r io.Reader, delim byte) (*LockedBuffer, error) {
 }
 
 /*
-NewBufferFromEntireReader reads from an io.Reader into an immutable buffer. It will continue reading until EOF.
+NewBufferFromFd reads exactly size bytes from a file descriptor, such as one received over a Unix socket or referring to a memory file, directly into an immutable LockedBuffer. The descriptor is closed afterwards, whether or not the read succeeded, so it must not be used or closed by the caller again.
+
+ErrInvalidLength is returned if fewer than size bytes could be read, in which case any data that was read is destroyed. A size of less than one returns a null buffer.
+*/
+func NewBufferFromFd(fd uintptr, size int) (*LockedBuffer, error) {
+	f := os.NewFile(fd, "memguard")
+	defer f.Close()
+
+	b, err := NewBufferFromReader(f, size)
+	if err != nil {
+		b.Destroy()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrInvalidLength
+		}
+		return newNullBuffer(), err
+	}
+	return b, nil
+}
+
+/*
+NewBufferFromConn reads exactly size bytes from a network connection, such as a credential sent by a client, directly into an immutable LockedBuffer. The read must complete before the given deadline, which is set on the connection for the duration of the call and cleared afterwards.
+
+If the data does not arrive in time, the timeout error returned by the connection is returned, which satisfies net.Error with Timeout reporting true. On this or any other error, any data that was read is destroyed and a null buffer is returned. A size of less than one returns a null buffer without reading anything.
+*/
+func NewBufferFromConn(conn net.Conn, size int, deadline time.Time) (*LockedBuffer, error) {
+	b := NewBuffer(size)
+	if b.Size() == 0 {
+		return b, nil
+	}
+
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		b.Destroy()
+		return newNullBuffer(), err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	if _, err := io.ReadFull(conn, b.Bytes()); err != nil {
+		b.Destroy()
+		return newNullBuffer(), err
+	}
+
+	b.Freeze()
+	return b, nil
+}
+
+/*
+NewBufferFromEntireReader reads from an io.Reader into an immutable buffer. It will continue reading until EOF.
+
+A nil error is returned precisely when we managed to read all the way until EOF. Any data read is returned in either case.
+*/
+func NewBufferFromEntireReader(r io.Reader) (*LockedBuffer, error) {
+	// Create a buffer with a data region of one page size.
+	b := NewBuffer(os.Getpagesize())
+
+	for read := 0; ; {
+		// Attempt to read some data from the reader.
+		n, err := r.Read(b.Bytes()[read:])
+
+		// Nothing read but no error, try again.
+		if n == 0 && err == nil {
+			continue
+		}
+
+		// 1) so either have data and no error
+		// 2) or have error and no data
+		// 3) or both have data and have error
+
+		// Increment the read count by the number of bytes that we just read.
+		read += n
+
+		if err != nil {
+			// Suppress EOF error
+			if err == io.EOF {
+				err = nil
+			}
+			// We're done, return the data.
+			if read == 0 {
+				// No data read.
+				b.Destroy()
+				return newNullBuffer(), err
+			}
+			d := NewBuffer(read)
+			d.Copy(b.Bytes()[:read])
+			d.Freeze()
+			b.Destroy()
+			return d, err
+		}
+
+		// If we've filled this buffer, grow it by another page size.
+		if len(b.Bytes()[read:]) == 0 {
+			d := NewBuffer(b.Size() + os.Getpagesize())
+			d.Copy(b.Bytes())
+			b.Destroy()
+			b = d
+		}
+	}
+}
+
+/*
+NewBufferRandom constructs an immutable buffer filled with cryptographically-secure random bytes. If random bytes cannot be read from core.RandReader, a null buffer is returned.
+*/
+func NewBufferRandom(size int) *LockedBuffer {
+	// Construct a buffer of the specified size.
+	b := NewBuffer(size)
+	if b.Size() == 0 {
+		return b
+	}
+
+	// Fill the buffer with random bytes.
+	if err := b.Access(true, core.Scramble); err != nil {
+		b.Destroy()
+		return newNullBuffer()
+	}
+
+	// Make the buffer immutable.
+	b.Freeze()
+
+	// Return the created Buffer object.
+	return b
+}
+
+/*
+NewBufferFromGenerator constructs an immutable buffer of a given size whose byte at each index i is gen(i), such as i%256 for a ramp. This creates predictable contents, for test fixtures or structured material, directly in guarded memory without an intermediate slice.
+
+core.ErrNullBuffer is returned, along with a null buffer, if the size is less than one.
+*/
+func NewBufferFromGenerator(size int, gen func(i int) byte) (*LockedBuffer, error) {
+	if size < 1 {
+		return newNullBuffer(), core.ErrNullBuffer
+	}
+
+	b := NewBuffer(size)
+	b.Access(true, func(data []byte) error {
+		for i := range data {
+			data[i] = gen(i)
+		}
+		return nil
+	})
+
+	b.Freeze()
+	return b, nil
+}
+
+// Freeze makes a LockedBuffer's memory immutable. The call can be reversed with Melt.
+func (b *LockedBuffer) Freeze() {
+	if b == nil {
+		return
+	}
+	b.Buffer.Freeze()
+}
+
+// Melt makes a LockedBuffer's memory mutable. The call can be reversed with Freeze. If the LockedBuffer has been frozen with FreezeImmutable, it is left unchanged; use TryMelt to be told.
+func (b *LockedBuffer) Melt() {
+	if b == nil {
+		return
+	}
+	b.Buffer.Melt()
+}
+
+// TryMelt is identical to Melt except that it reports whether the memory could be made mutable, returning core.ErrImmutable if the LockedBuffer has been frozen with FreezeImmutable.
+func (b *LockedBuffer) TryMelt() error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.TryMelt()
+}
+
+/*
+FreezeImmutable permanently makes a LockedBuffer's memory immutable. It is intended for secrets that are loaded once and never changed, such as a long-term signing key, and prevents code elsewhere from accidentally making them writable.
+
+Unlike Freeze, the call cannot be reversed: Melt will leave the memory as it is, and TryMelt and Protect will refuse to make it writable and return core.ErrImmutable instead. The LockedBuffer can still be read and destroyed as normal.
+
+An error is returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) FreezeImmutable() error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.FreezeImmutable()
+}
+
+/*
+Protect sets the protection of a LockedBuffer's memory. Passing true for both arguments is equivalent to Melt and passing only read is equivalent to Freeze.
+
+If read is false, the memory is made inaccessible for maximum hardening between uses. Directly accessing the slice returned by Bytes will then cause an access violation, but methods such as Copy and EqualTo continue to work by temporarily relaxing the protection. In this case write controls whether those methods are allowed to modify the contents.
+
+An error is returned if the LockedBuffer has been destroyed, or if write is true and it has been frozen with FreezeImmutable.
+*/
+func (b *LockedBuffer) Protect(read, write bool) error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.Protect(read, write)
+}
+
+/*
+SetAutoProtect sets whether a LockedBuffer's memory is kept inaccessible between operations, as the strongest protection against it being read by mistake. While enabled the memory is only made accessible for the duration of each call to a method such as Copy, EqualTo or Access, and is made inaccessible again before it returns. Freeze, Melt and Protect then only control whether those methods may modify the contents.
+
+Accessing the memory directly, through the slice returned by Bytes or any of the other views onto it, is unsafe by design in this state and causes an access violation. Use Access to work with the data instead. Disabling automatic protection makes the memory readable again.
+
+An error is returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) SetAutoProtect(enabled bool) error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.SetAutoProtect(enabled)
+}
+
+/*
+SetAdvice tells the operating system how the memory of a LockedBuffer should be treated, using a combination of the core.Advice flags, and overrides the defaults for that LockedBuffer alone. For example, a LockedBuffer whose memory is locked is excluded from core dumps by default, but a throwaway scratch buffer can be included with core.AdviceDoDump.
+
+Advice is only supported on Linux, and core.ErrAdviceUnsupported is returned elsewhere. core.ErrInvalidAdvice is returned if the flags are unknown or contradictory, and an error is returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) SetAdvice(flags int) error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.SetAdvice(flags)
+}
+
+/*
+Access calls a given function with the data of a LockedBuffer, temporarily relaxing the protection of its memory if it has been made inaccessible with Protect. If write is true the function may modify the data. The function must not retain the slice.
+
+An error is returned if the LockedBuffer has been destroyed, or if write is true and it is immutable. Otherwise the error returned by the function is forwarded.
+*/
+func (b *LockedBuffer) Access(write bool, fn func(data []byte) error) error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.Access(write, fn)
+}
+
+/*
+WithReadable calls a given function with the data of a LockedBuffer, making its memory readable for the duration of the call if it is inaccessible, such as after SetAutoProtect or Protect(false, ...). The memory is never made writable, and its protection is restored before WithReadable returns, even if the function panics. This is the safe way to read a LockedBuffer whose memory is inaccessible by default. The function must not modify or retain the slice.
+
+core.ErrBufferExpired is returned without calling the function if the LockedBuffer has been destroyed. Otherwise the error returned by the function is forwarded. Use Access to modify the data.
+*/
+func (b *LockedBuffer) WithReadable(fn func(data []byte) error) error {
+	return b.Access(false, fn)
+}
+
+/*
+Batch calls a given function with the data of a LockedBuffer made writable, so that many modifications can be made at the cost of a single change of protection. While SetAutoProtect is enabled, every call to a method such as CopyAt makes the memory writable and then inaccessible again; making the same writes inside one call to Batch changes the protection only once before the function runs and once after it returns, even if it panics. The function must not retain the slice.
+
+An error is returned without calling the function if the LockedBuffer is immutable or has been destroyed. Otherwise the error returned by the function is forwarded. Any changes it made before returning an error are kept.
+*/
+func (b *LockedBuffer) Batch(fn func(writable []byte) error) error {
+	return b.Access(true, fn)
+}
+
+/*
+Seal takes a LockedBuffer object and returns its contents encrypted inside a sealed Enclave object. The LockedBuffer is subsequently destroyed and its contents wiped.
+
+If Seal is called on a destroyed buffer, a nil enclave is returned.
+*/
+func (b *LockedBuffer) Seal() *Enclave {
+	if b == nil {
+		return nil
+	}
+	e, err := core.Seal(b.Buffer)
+	if err != nil {
+		if err == core.ErrBufferExpired {
+			return nil
+		}
+		core.Panic(err)
+	}
+	return &Enclave{e}
+}
+
+/*
+Copy performs a time-constant copy into a LockedBuffer. Move is preferred if the source is not also a LockedBuffer or if the source is no longer needed.
+
+The source is truncated if it is longer than the LockedBuffer, and the remainder of the LockedBuffer is wiped if it is shorter, so no part of the previous contents survives the copy. Use CopyAt to overwrite only part of the LockedBuffer, or CopyFit to be told if the copy fails.
+*/
+func (b *LockedBuffer) Copy(src []byte) {
+	b.CopyFit(src)
+}
+
+/*
+CopyAt performs a time-constant copy into a LockedBuffer at an offset. Move is preferred if the source is not also a LockedBuffer or if the source is no longer needed.
+
+Only the bytes that the source is copied over are changed; the rest of the LockedBuffer is left as it was.
+
+The source may overlap the LockedBuffer's own memory, in which case the copy behaves like memmove. If the offset is out of range, nothing is copied while bounds checking is enabled, but the call panics like an out of range slice expression once it has been disabled; see SetBoundsChecking. Use CopyAtChecked to be told if the copy fails.
+*/
+func (b *LockedBuffer) CopyAt(offset int, src []byte) {
+	b.copyAt(offset, src, false)
+}
+
+/*
+CopyAtChecked behaves exactly like CopyAt, but always validates the offset, whether or not bounds checking is enabled, and reports whether the copy could be made. As with CopyAt, a source that extends past the end of the LockedBuffer is truncated.
+
+core.ErrOutOfBounds is returned if the offset lies outside of the data, in which case nothing is copied. An error is also returned if the LockedBuffer is immutable or has been destroyed.
+*/
+func (b *LockedBuffer) CopyAtChecked(offset int, src []byte) error {
+	return b.copyAt(offset, src, true)
+}
+
+// Copies into a LockedBuffer at an offset, validating the offset if bounds checking is enabled or if always is true.
+func (b *LockedBuffer) copyAt(offset int, src []byte, always bool) error {
+	defer sanitizeStackIfEnabled()
+	return b.Access(true, func(data []byte) error {
+		if !checkOffset(data, offset, always) {
+			return core.ErrOutOfBounds
+		}
+		core.Copy(data[offset:], src)
+		return nil
+	})
+}
+
+/*
+CopyFit performs a time-constant copy of src into a LockedBuffer, truncating it if it is longer than the LockedBuffer and wiping the remainder of the LockedBuffer if it is shorter. None of the previous contents survive the copy. It behaves exactly like Copy, but reports whether the copy could be made.
+
+An error is returned if the LockedBuffer is immutable or has been destroyed.
+*/
+func (b *LockedBuffer) CopyFit(src []byte) error {
+	defer sanitizeStackIfEnabled()
+	return b.Access(true, func(data []byte) error {
+		core.Copy(data, src)
+		if len(src) < len(data) {
+			core.Wipe(data[len(src):])
+		}
+		return nil
+	})
+}
+
+/*
+CopyTo performs a time-constant copy of the contents of a LockedBuffer into a given slice, returning the number of bytes copied. This is the minimum of the size of the LockedBuffer and the length of the slice. An error is returned if the LockedBuffer has been destroyed.
+
+The slice is not protected in any way, so the caller should wipe it as soon as it is no longer needed.
+*/
+func (b *LockedBuffer) CopyTo(dst []byte) (int, error) {
+	defer sanitizeStackIfEnabled()
+	var n int
+	err := b.Access(false, func(data []byte) error {
+		core.Copy(dst, data)
+		n = len(data)
+		if len(dst) < n {
+			n = len(dst)
+		}
+		return nil
+	})
+	return n, err
+}
+
+/*
+CopyRange performs a time-constant copy of length bytes from a LockedBuffer starting at srcOff into another LockedBuffer starting at dstOff. The data is copied directly between the two regions of guarded memory. Both LockedBuffers may be the same, in which case the copy behaves like memmove.
+
+An error is returned if either LockedBuffer has been destroyed, if the destination is immutable, or if either range is out of bounds. Nothing is copied in these cases.
+*/
+func CopyRange(dst *LockedBuffer, dstOff int, src *LockedBuffer, srcOff, length int) error {
+	return accessPair(dst, true, src, false, func(d, s []byte) error {
+		if dstOff < 0 || srcOff < 0 || length < 0 || dstOff > len(d)-length || srcOff > len(s)-length {
+			return core.ErrOutOfBounds
+		}
+		core.Copy(d[dstOff:dstOff+length], s[srcOff:srcOff+length])
+		return nil
+	})
+}
+
+/*
+CopyFromBufferAt performs a time-constant copy of the data of a LockedBuffer starting at srcOff into dst, and returns the number of bytes copied. This is the minimum of the size of dst and the number of bytes after srcOff, so a field can be extracted from a larger LockedBuffer into one of the field's size without computing its length. Any remainder of dst is left unchanged. The data is copied directly between the two regions of guarded memory. See CopyRange to copy a range of a given length.
+
+An error is returned if either LockedBuffer has been destroyed, if dst is immutable, or if srcOff lies outside of the data of src. Nothing is copied in these cases.
+*/
+func (dst *LockedBuffer) CopyFromBufferAt(src *LockedBuffer, srcOff int) (int, error) {
+	var n int
+	err := accessPair(dst, true, src, false, func(d, s []byte) error {
+		if srcOff < 0 || srcOff > len(s) {
+			return core.ErrOutOfBounds
+		}
+		core.Copy(d, s[srcOff:])
+		n = len(s) - srcOff
+		if len(d) < n {
+			n = len(d)
+		}
+		return nil
+	})
+	return n, err
+}
+
+// CopyPolicy decides how CopyBufferPolicy handles LockedBuffers of different sizes.
+type CopyPolicy int
+
+const (
+	// Exact refuses to copy between LockedBuffers of different sizes.
+	Exact CopyPolicy = iota
+
+	// Truncate copies as many bytes as fit in the smaller of the two LockedBuffers, leaving the rest of the destination unchanged.
+	Truncate
+
+	// ZeroPad copies the whole source and wipes the remainder of the destination. It refuses a source that is larger than the destination.
+	ZeroPad
+)
+
+/*
+CopyBufferPolicy performs a time-constant copy of the contents of one LockedBuffer into another, handling a difference in their sizes according to an explicit policy rather than silently truncating. It returns the number of bytes copied from src. The data is copied directly between the two regions of guarded memory.
+
+ErrInvalidLength is returned if the policy is Exact and the sizes differ, or if it is ZeroPad and src is larger than dst. ErrInvalidCopyPolicy is returned for an unknown policy. An error is also returned if either LockedBuffer has been destroyed or if dst is immutable. Nothing is copied in these cases.
+*/
+func CopyBufferPolicy(dst, src *LockedBuffer, policy CopyPolicy) (int, error) {
+	if policy != Exact && policy != Truncate && policy != ZeroPad {
+		return 0, ErrInvalidCopyPolicy
+	}
+	var n int
+	err := accessPair(dst, true, src, false, func(d, s []byte) error {
+		switch {
+		case policy == Exact && len(s) != len(d):
+			return ErrInvalidLength
+		case policy == ZeroPad && len(s) > len(d):
+			return ErrInvalidLength
+		}
+		core.Copy(d, s)
+		n = len(s)
+		if len(d) < n {
+			n = len(d)
+		}
+		if policy == ZeroPad {
+			core.Wipe(d[n:])
+		}
+		return nil
+	})
+	return n, err
+}
+
+// Calls fn with the data of two LockedBuffers, which may be the same, after gaining read or write access to each. An error is returned if either has been destroyed or if write access is requested to one that is immutable.
+func accessPair(x *LockedBuffer, xWrite bool, y *LockedBuffer, yWrite bool, fn func(x, y []byte) error) error {
+	if !x.IsAlive() || !y.IsAlive() {
+		return core.ErrBufferExpired
+	}
+	if x.Buffer == y.Buffer {
+		return x.Access(xWrite || yWrite, func(data []byte) error {
+			return fn(data, data)
+		})
+	}
+
+	// Always lock the two buffers in the same order so that concurrent calls with the arguments reversed cannot deadlock.
+	if uintptr(unsafe.Pointer(x.Buffer)) < uintptr(unsafe.Pointer(y.Buffer)) {
+		return x.Access(xWrite, func(dx []byte) error {
+			return y.Access(yWrite, func(dy []byte) error {
+				return fn(dx, dy)
+			})
+		})
+	}
+	return y.Access(yWrite, func(dy []byte) error {
+		return x.Access(xWrite, func(dx []byte) error {
+			return fn(dx, dy)
+		})
+	})
+}
+
+/*
+Swap exchanges the contents of two LockedBuffers of the same size in place, using a guarded temporary buffer to hold one of them during the exchange.
+
+ErrInvalidLength is returned if the sizes differ. An error is also returned if either LockedBuffer is immutable or has been destroyed. Nothing is modified in these cases.
+*/
+func Swap(a, b *LockedBuffer) error {
+	return accessPair(a, true, b, true, func(x, y []byte) error {
+		if len(x) != len(y) {
+			return ErrInvalidLength
+		}
+		if len(x) == 0 || &x[0] == &y[0] {
+			return nil
+		}
+
+		t := NewBuffer(len(x))
+		defer t.Destroy()
+		core.Copy(t.Bytes(), x)
+		core.Copy(x, y)
+		core.Copy(y, t.Bytes())
+		return nil
+	})
+}
+
+/*
+CondSwap exchanges the contents of two LockedBuffers of the same size if cond is 1, and leaves them unchanged if cond is 0. The same memory is read and written in the same order in either case, so the condition is not revealed by a data-dependent branch or by the running time.
+
+ErrInvalidCondition is returned if cond is neither 0 nor 1 and ErrInvalidLength is returned if the sizes differ. An error is also returned if either LockedBuffer is immutable or has been destroyed. Nothing is modified in these cases.
+*/
+func CondSwap(a, b *LockedBuffer, cond int) error {
+	if cond != 0 && cond != 1 {
+		return ErrInvalidCondition
+	}
+	return accessPair(a, true, b, true, func(x, y []byte) error {
+		if len(x) != len(y) {
+			return ErrInvalidLength
+		}
+		if len(x) == 0 || &x[0] == &y[0] {
+			return nil
+		}
+
+		// Every bit of the mask is set if and only if cond is 1.
+		mask := -byte(cond)
+		for i := range x {
+			t := (x[i] ^ y[i]) & mask
+			x[i] ^= t
+			y[i] ^= t
+		}
+		return nil
+	})
+}
+
+/*
+AppendTo copies the whole of a LockedBuffer into another LockedBuffer starting at a given offset, and returns the offset just after the copied data. This allows a message with a fixed layout to be assembled from several fragments entirely within guarded memory:
+
+	end, err := header.AppendTo(msg, 0)
+	end, err = body.AppendTo(msg, end)
+
+An error is returned and the offset is returned unchanged if the data would not fit inside the destination, if the destination is immutable, or if either LockedBuffer has been destroyed. See CopyRange.
+*/
+func (src *LockedBuffer) AppendTo(dst *LockedBuffer, at int) (int, error) {
+	n := src.Size()
+	if err := CopyRange(dst, at, src, 0, n); err != nil {
+		return at, err
+	}
+	return at + n, nil
+}
+
+/*
+MoveInto copies the whole of a LockedBuffer into another LockedBuffer starting at a given offset and then destroys the source, so that the data exists in exactly one place afterwards. This is the counterpart of MoveAt for fragments that are already held in guarded memory:
+
+	if err := header.MoveInto(msg, 0); err != nil {
+		...
+	}
+	if err := body.MoveInto(msg, header.Size()); err != nil {
+		...
+	}
+
+Note that the size of the source is no longer available once it has been moved. An error is returned if the data would not fit inside the destination, if the destination is immutable, if either LockedBuffer has been destroyed, or, with ErrSelfMove, if they are the same LockedBuffer. Nothing is copied and the source is left intact in these cases. See AppendTo.
+*/
+func (src *LockedBuffer) MoveInto(dst *LockedBuffer, at int) error {
+	if !src.IsAlive() || !dst.IsAlive() {
+		return core.ErrBufferExpired
+	}
+	if src.Buffer == dst.Buffer {
+		return ErrSelfMove
+	}
+	if err := CopyRange(dst, at, src, 0, src.Size()); err != nil {
+		return err
+	}
+	src.Destroy()
+	return nil
+}
+
+/*
+ConcatMany joins the contents of any number of LockedBuffers, in order, into a new mutable LockedBuffer. The total size is computed up front so that the result is allocated once, and each source is copied straight into it in turn, being made readable only for the duration of its copy. The sources are left unchanged. This makes ConcatMany the efficient way to assemble a secret from many fragments, where joining them a pair at a time would allocate and copy a new LockedBuffer for every fragment.
+
+A null buffer is returned if the total size is zero. If any LockedBuffer has been destroyed, a null buffer is returned along with core.ErrBufferExpired.
+*/
+func ConcatMany(buffers ...*LockedBuffer) (*LockedBuffer, error) {
+	total := 0
+	for _, b := range buffers {
+		if !b.IsAlive() {
+			return newNullBuffer(), core.ErrBufferExpired
+		}
+		total += b.Size()
+	}
+
+	out := NewBuffer(total)
+	dst := out.Bytes()
+	for _, b := range buffers {
+		if err := b.Access(false, func(data []byte) error {
+			if len(data) > len(dst) {
+				return ErrInvalidLength // Grown since the total was computed.
+			}
+			core.Copy(dst, data)
+			dst = dst[len(data):]
+			return nil
+		}); err != nil {
+			out.Destroy()
+			return newNullBuffer(), err
+		}
+	}
+	return out, nil
+}
+
+/*
+Move performs a time-constant move into a LockedBuffer. The source is wiped after the bytes are copied.
+*/
+func (b *LockedBuffer) Move(src []byte) {
+	b.MoveAt(0, src)
+}
+
+/*
+MoveAt performs a time-constant move into a LockedBuffer at an offset. The source is wiped after the bytes are copied.
+
+If the source overlaps the LockedBuffer's own memory, only the part of it that was not overwritten is wiped. If the offset is out of range, nothing is copied but the source is still wiped while bounds checking is enabled. Once it has been disabled the call panics like an out of range slice expression instead, leaving the source unchanged; see SetBoundsChecking. Use MoveAtChecked to be told if the move fails.
+*/
+func (b *LockedBuffer) MoveAt(offset int, src []byte) {
+	b.moveAt(offset, src, false)
+}
+
+/*
+MoveAtChecked behaves exactly like MoveAt, but always validates the offset, whether or not bounds checking is enabled, and reports whether the move could be made.
+
+core.ErrOutOfBounds is returned if the offset lies outside of the data, in which case nothing is copied but the source is still wiped. An error is also returned if the LockedBuffer is immutable or has been destroyed, in which case the source is left unchanged.
+*/
+func (b *LockedBuffer) MoveAtChecked(offset int, src []byte) error {
+	return b.moveAt(offset, src, true)
+}
+
+// Moves into a LockedBuffer at an offset, validating the offset if bounds checking is enabled or if always is true.
+func (b *LockedBuffer) moveAt(offset int, src []byte, always bool) error {
+	defer sanitizeStackIfEnabled()
+	return b.Access(true, func(data []byte) error {
+		if !checkOffset(data, offset, always) {
+			core.Wipe(src)
+			return core.ErrOutOfBounds
+		}
+		core.Move(data[offset:], src)
+		return nil
+	})
+}
+
+/*
+Consume removes the first n bytes from a LockedBuffer and returns them inside a new mutable LockedBuffer. The LockedBuffer shrinks in place to hold the remaining bytes and the consumed region is wiped. This is useful for incrementally parsing data.
+
+The LockedBuffer must be mutable and n must not exceed its size. Consuming zero bytes returns a null buffer.
+*/
+func (b *LockedBuffer) Consume(n int) (*LockedBuffer, error) {
+	if b == nil {
+		return newNullBuffer(), core.ErrBufferExpired
+	}
+	c, err := b.Buffer.Consume(n)
+	if err != nil {
+		if err == core.ErrNullBuffer {
+			return newNullBuffer(), nil
+		}
+		return newNullBuffer(), err
+	}
+	return newBuffer(c), nil
+}
+
+/*
+Insert returns a new LockedBuffer holding the contents of a LockedBuffer with some data inserted at a given offset, which may be anywhere from the start to the end of the data inclusive. The original LockedBuffer is destroyed and the source data is left unchanged.
+
+The new LockedBuffer is immutable if the original was immutable. An error is returned if the offset is out of bounds or if the LockedBuffer has been destroyed, in which case it is left unchanged.
+*/
+func (b *LockedBuffer) Insert(offset int, data []byte) (*LockedBuffer, error) {
+	return b.splice(offset, 0, data)
+}
+
+/*
+AppendString returns a new LockedBuffer holding the contents of a LockedBuffer followed by the bytes of a string, which are copied straight from the string's memory without an intermediate slice. This allows text such as a connection string or URL containing a secret to be assembled inside guarded memory:
+
+	b, err = b.AppendString("@db.example.com:5432")
+
+The original LockedBuffer is destroyed, and the new one is immutable if the original was immutable. Strings cannot be wiped, so any secret passed as a string remains in ordinary memory regardless. An error is returned if the LockedBuffer has been destroyed, in which case it is left unchanged.
+*/
+func (b *LockedBuffer) AppendString(str string) (*LockedBuffer, error) {
+	// View the bytes of the string without copying them. They are only ever read.
+	var data []byte
+	if len(str) > 0 {
+		h := (*reflect.SliceHeader)(unsafe.Pointer(&data))
+		h.Data = (*reflect.StringHeader)(unsafe.Pointer(&str)).Data
+		h.Len = len(str)
+		h.Cap = len(str)
+	}
+
+	c, err := b.splice(b.Size(), 0, data)
+	runtime.KeepAlive(str)
+	return c, err
+}
+
+/*
+Delete returns a new LockedBuffer holding the contents of a LockedBuffer with length bytes removed starting at a given offset. The original LockedBuffer is destroyed, and deleting every byte returns a null buffer.
+
+The new LockedBuffer is immutable if the original was immutable. An error is returned if the range is out of bounds or if the LockedBuffer has been destroyed, in which case it is left unchanged.
+*/
+func (b *LockedBuffer) Delete(offset, length int) (*LockedBuffer, error) {
+	return b.splice(offset, length, nil)
+}
+
+// Replaces n bytes of data at an offset with the given data in a new LockedBuffer and destroys the original.
+func (b *LockedBuffer) splice(offset, n int, insert []byte) (*LockedBuffer, error) {
+	mutable := b.IsMutable()
+
+	c := newNullBuffer()
+	if err := b.Access(false, func(data []byte) error {
+		if offset < 0 || n < 0 || offset > len(data)-n {
+			return core.ErrOutOfBounds
+		}
+		c = NewBuffer(len(data) - n + len(insert))
+		out := c.Bytes()
+		core.Copy(out, data[:offset])
+		core.Copy(out[offset:], insert)
+		core.Copy(out[offset+len(insert):], data[offset+n:])
+		return nil
+	}); err != nil {
+		return c, err
+	}
+	b.Destroy()
+
+	if !mutable {
+		c.Freeze()
+	}
+	return c, nil
+}
+
+/*
+Duplicate returns an independent copy of a LockedBuffer that is a faithful replica of the original: it is mutable, immutable, or inaccessible exactly when the original is, keeps its memory inaccessible between operations if SetAutoProtect is enabled on the original, is permanently frozen if the original was frozen with FreezeImmutable, and is left unlocked if the original was created with WithoutLock. The copy is unaffected by later changes to or the destruction of the original, which is left unchanged.
+
+An error is returned if the LockedBuffer has been destroyed. See ToReadOnlyClone for a copy that can be shared safely with readers.
+*/
+func (b *LockedBuffer) Duplicate() (*LockedBuffer, error) {
+	if b == nil {
+		return newNullBuffer(), core.ErrBufferExpired
+	}
+	buf, err := b.Buffer.Duplicate()
+	if err != nil {
+		if err == core.ErrNullBuffer {
+			return newNullBuffer(), nil
+		}
+		return newNullBuffer(), err
+	}
+	return newBuffer(buf), nil
+}
+
+/*
+ToReadOnlyClone returns an independent copy of a LockedBuffer that has been permanently frozen with FreezeImmutable. It can be safely shared with any number of readers, none of which can modify it, and is unaffected by later changes to or the destruction of the original. The original is left unchanged.
+
+An error is returned if the LockedBuffer has been destroyed. Cloning an empty LockedBuffer returns a null buffer.
+*/
+func (b *LockedBuffer) ToReadOnlyClone() (*LockedBuffer, error) {
+	c := newNullBuffer()
+	if err := b.Access(false, func(data []byte) error {
+		c = NewBuffer(len(data))
+		c.Copy(data)
+		return nil
+	}); err != nil {
+		return c, err
+	}
+
+	if c.IsAlive() {
+		if err := c.FreezeImmutable(); err != nil {
+			c.Destroy()
+			return newNullBuffer(), err
+		}
+	}
+	return c, nil
+}
+
+/*
+CloneResize returns an independent copy of a LockedBuffer of a given size, holding the contents of the original truncated or zero-extended to fit, in a single allocation. The copy is immutable if the original is immutable, and is unaffected by later changes to or the destruction of the original, which is left unchanged.
+
+An error is returned if the LockedBuffer has been destroyed. A size of less than one returns a null buffer.
+*/
+func (b *LockedBuffer) CloneResize(size int) (*LockedBuffer, error) {
+	mutable := b.IsMutable()
+
+	c := newNullBuffer()
+	if err := b.Access(false, func(data []byte) error {
+		c = NewBuffer(size)
+		c.Copy(data)
+		return nil
+	}); err != nil {
+		return c, err
+	}
+
+	if !mutable {
+		c.Freeze()
+	}
+	return c, nil
+}
+
+/*
+Touch reads every page of the memory backing a LockedBuffer so that it is faulted in ahead of time, and verifies that it is resident. This is useful for latency-sensitive code that cannot tolerate a page fault in the middle of an operation. The data is not modified.
+
+An error is returned if the LockedBuffer has been destroyed, or if its memory is not resident after being touched. Residency can only be verified on Linux.
+*/
+func (b *LockedBuffer) Touch() error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.Touch()
+}
+
+/*
+CompactResidency releases the physical memory backing the pages of a LockedBuffer that lie entirely beyond its first usedLen bytes, for large LockedBuffers of which only a prefix is in use. The LockedBuffer keeps its full size, and the released region reads as zeroes, discarding what was there. Released pages are locked again by the kernel as they are faulted back in by being accessed.
 
-A nil error is returned precisely when we managed to read all the way until EOF. Any data read is returned in either case.
+The guarantees for the released region are weaker: its memory is no longer reserved, so accessing it later may fail under memory pressure like any other allocation. The first usedLen bytes are unaffected. Compacting is only supported on Linux 4.4 and later.
+
+An error is returned if usedLen is out of bounds, if the LockedBuffer is immutable, shared or allocated from an Arena, or if it has been destroyed. core.ErrAdviceUnsupported is returned on other platforms.
 */
-func NewBufferFromEntireReader(r io.Reader) (*LockedBuffer, error) {
-	// Create a buffer with a data region of one page size.
-	b := NewBuffer(os.Getpagesize())
+func (b *LockedBuffer) CompactResidency(usedLen int) error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.CompactResidency(usedLen)
+}
 
-	for read := 0; ; {
-		// Attempt to read some data from the reader.
-		n, err := r.Read(b.Bytes()[read:])
+/*
+Grow extends a LockedBuffer by n bytes, which are appended after the existing data and set to zero. The data is moved to a new region of guarded memory and the old region is destroyed, so any slice previously returned by Bytes must not be used afterwards.
 
-		// Nothing read but no error, try again.
-		if n == 0 && err == nil {
-			continue
+An error is returned if the LockedBuffer is immutable or has been destroyed, or if n is negative.
+*/
+func (b *LockedBuffer) Grow(n int) error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.Grow(n)
+}
+
+/*
+GrowRandom extends a LockedBuffer by n bytes and fills them with cryptographically-secure random bytes, leaving the existing data intact. This is useful for deriving additional key material. See Grow for how the LockedBuffer is extended.
+
+The random bytes are generated before the LockedBuffer is modified, so it is left unchanged if they cannot be read.
+*/
+func (b *LockedBuffer) GrowRandom(n int) error {
+	if !b.IsAlive() {
+		return core.ErrBufferExpired
+	}
+	if !b.IsMutable() {
+		return core.ErrBufferImmutable
+	}
+	if n < 0 {
+		return core.ErrOutOfBounds
+	}
+	if n == 0 {
+		return nil
+	}
+
+	// Generate the random bytes in guarded memory.
+	r, err := core.NewBuffer(n)
+	if err != nil {
+		return err
+	}
+	defer r.Destroy()
+	if err := core.Scramble(r.Data()); err != nil {
+		return err
+	}
+
+	// Append them to the data.
+	if err := b.Grow(n); err != nil {
+		return err
+	}
+	return b.Access(true, func(data []byte) error {
+		core.Copy(data[len(data)-n:], r.Data())
+		return nil
+	})
+}
+
+/*
+Slice returns a copy of the region of a LockedBuffer of a given length starting at a given offset, inside a new LockedBuffer. Unlike slicing the memory returned by Bytes, the new LockedBuffer does not alias the original and so remains valid after the original is destroyed. It is immutable if the original is immutable.
+
+An error is returned if the region is out of bounds or if the LockedBuffer has been destroyed. Requesting zero bytes returns a null buffer.
+*/
+func (b *LockedBuffer) Slice(offset, length int) (*LockedBuffer, error) {
+	mutable := b.IsMutable()
+
+	c := newNullBuffer()
+	if err := b.Access(false, func(data []byte) error {
+		if offset < 0 || length < 0 || offset > len(data)-length {
+			return core.ErrOutOfBounds
 		}
+		c = NewBuffer(length)
+		c.Copy(data[offset : offset+length])
+		return nil
+	}); err != nil {
+		return c, err
+	}
 
-		// 1) so either have data and no error
-		// 2) or have error and no data
-		// 3) or both have data and have error
+	if !mutable {
+		c.Freeze()
+	}
+	return c, nil
+}
 
-		// Increment the read count by the number of bytes that we just read.
-		read += n
+/*
+SplitByte splits the contents of a LockedBuffer around each occurrence of a delimiter, such as the fields of a colon-separated credential, and returns a copy of each segment inside its own LockedBuffer. The segments are copied directly between guarded regions and can be destroyed independently of each other and of the original, which is left unchanged. They are immutable if the original is immutable.
 
-		if err != nil {
-			// Suppress EOF error
-			if err == io.EOF {
-				err = nil
+An empty segment, such as between two adjacent delimiters, is returned as a null buffer. The positions of the delimiters are not hidden from timing side-channels. An error is returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) SplitByte(delim byte) ([]*LockedBuffer, error) {
+	mutable := b.IsMutable()
+
+	var segments []*LockedBuffer
+	if err := b.Access(false, func(data []byte) error {
+		for start := 0; ; {
+			end := bytes.IndexByte(data[start:], delim)
+			if end < 0 {
+				end = len(data)
+			} else {
+				end += start
 			}
-			// We're done, return the data.
-			if read == 0 {
-				// No data read.
-				b.Destroy()
-				return newNullBuffer(), err
+
+			c := NewBuffer(end - start)
+			c.Copy(data[start:end])
+			segments = append(segments, c)
+
+			if end == len(data) {
+				return nil
 			}
-			d := NewBuffer(read)
-			d.Copy(b.Bytes()[:read])
-			d.Freeze()
-			b.Destroy()
-			return d, err
+			start = end + 1
 		}
+	}); err != nil {
+		return nil, err
+	}
 
-		// If we've filled this buffer, grow it by another page size.
-		if len(b.Bytes()[read:]) == 0 {
-			d := NewBuffer(b.Size() + os.Getpagesize())
-			d.Copy(b.Bytes())
-			b.Destroy()
-			b = d
+	if !mutable {
+		for _, c := range segments {
+			c.Freeze()
 		}
 	}
+	return segments, nil
 }
 
 /*
-NewBufferRandom constructs an immutable buffer filled with cryptographically-secure random bytes.
+Scramble attempts to overwrite the data with cryptographically-secure random bytes.
 */
-func NewBufferRandom(size int) *LockedBuffer {
-	// Construct a buffer of the specified size.
-	b := NewBuffer(size)
-	if b.Size() == 0 {
-		return b
+func (b *LockedBuffer) Scramble() {
+	if err := b.Access(true, core.Scramble); err != nil && err != core.ErrBufferExpired && err != core.ErrBufferImmutable {
+		core.Panic(err)
 	}
+}
 
-	// Fill the buffer with random bytes.
-	b.Scramble()
+/*
+Clear overwrites the data of a LockedBuffer with zeros in place. The LockedBuffer stays allocated with the same size and protection, and can be written to and used again afterwards. A frozen or inaccessible LockedBuffer is cleared too, by temporarily making its memory writable and then restoring its protection.
 
-	// Make the buffer immutable.
-	b.Freeze()
+Clear differs from the other ways of disposing of a secret:
 
-	// Return the created Buffer object.
-	return b
-}
+	Clear    zeros the data but keeps the LockedBuffer, for reuse
+	Wipe     zeros the data only if the LockedBuffer is mutable, and does not report an error
+	Destroy  wipes the data and releases the memory, after which the LockedBuffer cannot be used
 
-// Freeze makes a LockedBuffer's memory immutable. The call can be reversed with Melt.
-func (b *LockedBuffer) Freeze() {
-	b.Buffer.Freeze()
+An error is returned if the LockedBuffer has been destroyed. It is left unchanged and core.ErrImmutable is returned if it has been frozen with FreezeImmutable, since its contents can then only be disposed of with Destroy.
+*/
+func (b *LockedBuffer) Clear() error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+	return b.Buffer.Clear()
 }
 
-// Melt makes a LockedBuffer's memory mutable. The call can be reversed with Freeze.
-func (b *LockedBuffer) Melt() {
-	b.Buffer.Melt()
+/*
+Wipe attempts to overwrite the data with zeros. Unlike Clear, it leaves a LockedBuffer that has been frozen unchanged.
+*/
+func (b *LockedBuffer) Wipe() {
+	b.Access(true, func(data []byte) error {
+		core.Wipe(data)
+		return nil
+	})
 }
 
 /*
-Seal takes a LockedBuffer object and returns its contents encrypted inside a sealed Enclave object. The LockedBuffer is subsequently destroyed and its contents wiped.
+Map applies a given function to every byte of a LockedBuffer in place, for example to case fold a passphrase. The function is called with each byte in turn and its result replaces the byte, so the data never leaves guarded memory.
 
-If Seal is called on a destroyed buffer, a nil enclave is returned.
+An error is returned if the LockedBuffer is immutable or has been destroyed.
 */
-func (b *LockedBuffer) Seal() *Enclave {
-	e, err := core.Seal(b.Buffer)
-	if err != nil {
-		if err == core.ErrBufferExpired {
-			return nil
+func (b *LockedBuffer) Map(fn func(byte) byte) error {
+	return b.Access(true, func(data []byte) error {
+		for i := range data {
+			data[i] = fn(data[i])
 		}
-		core.Panic(err)
-	}
-	return &Enclave{e}
+		return nil
+	})
 }
 
 /*
-Copy performs a time-constant copy into a LockedBuffer. Move is preferred if the source is not also a LockedBuffer or if the source is no longer needed.
+RotateLeft rotates the data of a LockedBuffer in place by n bytes towards the start, so that the byte at index n moves to index 0 and the first n bytes wrap around to the end. The rotation is taken modulo the size of the LockedBuffer, and a negative n rotates to the right. The data never leaves guarded memory.
+
+An error is returned if the LockedBuffer is immutable or has been destroyed.
 */
-func (b *LockedBuffer) Copy(src []byte) {
-	b.CopyAt(0, src)
+func (b *LockedBuffer) RotateLeft(n int) error {
+	return b.rotate(n, false)
 }
 
 /*
-CopyAt performs a time-constant copy into a LockedBuffer at an offset. Move is preferred if the source is not also a LockedBuffer or if the source is no longer needed.
+RotateRight rotates the data of a LockedBuffer in place by n bytes towards the end. It is equivalent to RotateLeft with the opposite sign of n.
 */
-func (b *LockedBuffer) CopyAt(offset int, src []byte) {
-	if !b.IsAlive() {
-		return
+func (b *LockedBuffer) RotateRight(n int) error {
+	return b.rotate(n, true)
+}
+
+// Rotates the data left by n bytes, or right if right is true.
+func (b *LockedBuffer) rotate(n int, right bool) error {
+	return b.Access(true, func(data []byte) error {
+		if len(data) == 0 {
+			return nil
+		}
+		n %= len(data)
+		if right {
+			n = -n
+		}
+		if n < 0 {
+			n += len(data)
+		}
+
+		// Rotate by reversing each part and then the whole.
+		reverse(data[:n])
+		reverse(data[n:])
+		reverse(data)
+		return nil
+	})
+}
+
+// Reverses a slice in place.
+func reverse(buf []byte) {
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
 	}
+}
 
-	b.Lock()
-	defer b.Unlock()
+/*
+Bit reports whether the bit at a given index of a LockedBuffer is set. Bits are numbered from the least significant bit of the first byte, so bit i is bit i%8 of byte i/8.
 
-	core.Copy(b.Bytes()[offset:], src)
+An error is returned if the index is not less than eight times the size of the buffer, or if the buffer has been destroyed.
+*/
+func (b *LockedBuffer) Bit(i int) (bool, error) {
+	var v bool
+	err := b.Access(false, func(data []byte) error {
+		if i < 0 || i >= len(data)*8 {
+			return core.ErrOutOfBounds
+		}
+		v = data[i/8]&(1<<uint(i%8)) != 0
+		return nil
+	})
+	return v, err
 }
 
 /*
-Move performs a time-constant move into a LockedBuffer. The source is wiped after the bytes are copied.
+SetBit sets or clears the bit at a given index of a LockedBuffer, numbered as described for Bit. No other bits are changed.
+
+An error is returned if the index is out of range, or if the buffer is immutable or has been destroyed.
 */
-func (b *LockedBuffer) Move(src []byte) {
-	b.MoveAt(0, src)
+func (b *LockedBuffer) SetBit(i int, v bool) error {
+	return b.Access(true, func(data []byte) error {
+		if i < 0 || i >= len(data)*8 {
+			return core.ErrOutOfBounds
+		}
+		if v {
+			data[i/8] |= 1 << uint(i%8)
+		} else {
+			data[i/8] &^= 1 << uint(i%8)
+		}
+		return nil
+	})
 }
 
 /*
-MoveAt performs a time-constant move into a LockedBuffer at an offset. The source is wiped after the bytes are copied.
+Size gives you the length of a given LockedBuffer's data segment. A destroyed LockedBuffer will have a size of zero.
 */
-func (b *LockedBuffer) MoveAt(offset int, src []byte) {
-	if !b.IsAlive() {
+func (b *LockedBuffer) Size() int {
+	return len(b.Bytes())
+}
+
+/*
+Destroy wipes and frees the underlying memory of a LockedBuffer. The LockedBuffer will not be accessible or usable after this calls is made.
+
+If readers obtained with Acquire are still outstanding, the LockedBuffer is instead destroyed as soon as the last of them is released, and remains usable until then. Purge and SafeExit do not wait.
+*/
+func (b *LockedBuffer) Destroy() {
+	if b == nil {
 		return
 	}
+	b.Buffer.DestroyWhenReleased()
+}
+
+/*
+Acquire registers a reader of a LockedBuffer that is shared between goroutines, such as an entry in a cache of secrets. While the reader holds the acquisition, Destroy does not free the memory: the LockedBuffer stays alive and usable until release has been called by every reader, at which point it is destroyed. The readers do not need to hold a lock while using it, and so can safely outlive another goroutine's decision to destroy it.
+
+It returns ok as false if the LockedBuffer has already been destroyed, or if Destroy has been called and is waiting for existing readers, in which case it must not be used. The release function must be called once the reader is done with the LockedBuffer; calling it again does nothing.
 
-	b.Lock()
-	defer b.Unlock()
+	release, ok := b.Acquire()
+	if !ok {
+		return errGone
+	}
+	defer release()
+	// use b
 
-	core.Move(b.Bytes()[offset:], src)
+Purge and SafeExit ignore acquisitions and destroy the LockedBuffer immediately.
+*/
+func (b *LockedBuffer) Acquire() (release func(), ok bool) {
+	if b == nil {
+		return func() {}, false
+	}
+	r, ok := b.Buffer.Acquire()
+	return func() {
+		r()
+		runtime.KeepAlive(b)
+	}, ok
 }
 
 /*
-Scramble attempts to overwrite the data with cryptographically-secure random bytes.
+OnDestroy registers a function to be called once a LockedBuffer has been destroyed, after its memory has been wiped, so that associated resources such as file descriptors or timers can be cleaned up with it. Functions are called in the reverse order to which they were registered, whether the LockedBuffer is destroyed by Destroy, Purge, DestroyAllExcept or SafeExit.
+
+Destroying the LockedBuffer again from within a function does nothing. The functions must not call Purge or use Enclaves, since Purge calls them while holding the session key. If the LockedBuffer has already been destroyed, the function is called immediately.
 */
-func (b *LockedBuffer) Scramble() {
-	if !b.IsAlive() {
+func (b *LockedBuffer) OnDestroy(fn func()) {
+	if b == nil {
+		fn()
 		return
 	}
+	b.Buffer.OnDestroy(fn)
+}
+
+/*
+IsAlive returns a boolean value indicating if a LockedBuffer is alive, i.e. that it has not been destroyed.
+*/
+func (b *LockedBuffer) IsAlive() bool {
+	return b != nil && b.Buffer.Alive()
+}
 
-	b.Buffer.Scramble()
+/*
+IsMutable returns a boolean value indicating if a LockedBuffer is mutable.
+*/
+func (b *LockedBuffer) IsMutable() bool {
+	return b != nil && b.Buffer.Mutable()
 }
 
+// BufferState describes whether a LockedBuffer is alive and, if so, whether it is mutable.
+type BufferState int
+
+const (
+	// StateDestroyed is the state of a LockedBuffer that is nil, null, or has been destroyed.
+	StateDestroyed BufferState = iota
+
+	// StateMutable is the state of a live LockedBuffer whose data can be modified.
+	StateMutable
+
+	// StateImmutable is the state of a live LockedBuffer that has been frozen.
+	StateImmutable
+)
+
 /*
-Wipe attempts to overwrite the data with zeros.
+State returns the state of a LockedBuffer. It may be called on a nil LockedBuffer, which is reported as destroyed.
 */
-func (b *LockedBuffer) Wipe() {
+func (b *LockedBuffer) State() BufferState {
 	if !b.IsAlive() {
-		return
+		return StateDestroyed
+	}
+	if b.IsMutable() {
+		return StateMutable
 	}
+	return StateImmutable
+}
 
-	b.Lock()
-	defer b.Unlock()
+/*
+Readable reports whether the data of a LockedBuffer can currently be read directly through the slice returned by Bytes, or any of the other views onto it, without causing an access violation. It is false if the LockedBuffer has been destroyed or its memory has been made inaccessible, such as by SetAutoProtect or Protect(false, ...), in which case it must be read through WithReadable or Access instead.
 
-	core.Wipe(b.Bytes())
+The state can be changed by other goroutines at any time, so it is only reliable while no other goroutine changes the LockedBuffer's protection.
+*/
+func (b *LockedBuffer) Readable() bool {
+	return b != nil && b.Buffer.Readable()
 }
 
 /*
-Size gives you the length of a given LockedBuffer's data segment. A destroyed LockedBuffer will have a size of zero.
+Writable reports whether the data of a LockedBuffer can currently be written directly through the slice returned by Bytes without causing an access violation. It is false if Readable is false or if the LockedBuffer is immutable. An inaccessible but mutable LockedBuffer can still be modified through Batch or Access.
 */
-func (b *LockedBuffer) Size() int {
-	return len(b.Bytes())
+func (b *LockedBuffer) Writable() bool {
+	return b != nil && b.Buffer.Writable()
 }
 
 /*
-Destroy wipes and frees the underlying memory of a LockedBuffer. The LockedBuffer will not be accessible or usable after this calls is made.
+IsDirty reports whether the contents of a LockedBuffer may have been modified since it was created or since ClearDirty was last called. This allows, for example, a secret store to skip persisting buffers that have not changed.
+
+The flag is set by every operation that is granted write access to the data, such as Copy, Move, Map, Wipe or Access with write set, whether or not the contents actually change. This includes the constructors that fill a new LockedBuffer, so ClearDirty should be called once a buffer has been loaded. Writes made directly through the slice returned by Bytes are not tracked. A destroyed LockedBuffer is never dirty.
 */
-func (b *LockedBuffer) Destroy() {
-	b.Buffer.Destroy()
+func (b *LockedBuffer) IsDirty() bool {
+	return b != nil && b.Buffer.Dirty()
+}
+
+// ClearDirty resets the flag reported by IsDirty, for example once the contents of a LockedBuffer have been persisted.
+func (b *LockedBuffer) ClearDirty() {
+	if b == nil {
+		return
+	}
+	b.Buffer.ClearDirty()
 }
 
 /*
-IsAlive returns a boolean value indicating if a LockedBuffer is alive, i.e. that it has not been destroyed.
+EqualTo performs a time-constant comparison on the contents of a LockedBuffer with a given buffer.
+
+If the LockedBuffer is nil or has been destroyed, false is returned together with core.ErrBufferExpired, so that a destroyed buffer cannot be mistaken for one holding different data. This is also the case for the null buffer that represents an empty value, as it holds no memory.
 */
-func (b *LockedBuffer) IsAlive() bool {
-	return b.Buffer.Alive()
+func (b *LockedBuffer) EqualTo(buf []byte) (bool, error) {
+	var equal bool
+	err := b.Access(false, func(data []byte) error {
+		equal = core.Equal(data, buf)
+		return nil
+	})
+	return equal, err
 }
 
 /*
-IsMutable returns a boolean value indicating if a LockedBuffer is mutable.
+ConstantTimeIndex returns the byte at a given index of a LockedBuffer. Every byte of the buffer is read and combined using constant-time selection, so neither the running time nor the pattern of memory accesses depends on the index. This protects table lookups, such as S-boxes, against cache-timing attacks at the cost of being linear in the size of the buffer.
+
+An error is returned if the index is out of range or if the buffer has been destroyed.
 */
-func (b *LockedBuffer) IsMutable() bool {
-	return b.Buffer.Mutable()
+func (b *LockedBuffer) ConstantTimeIndex(i int) (byte, error) {
+	var v byte
+	err := b.Access(false, func(data []byte) error {
+		if i < 0 || i >= len(data) {
+			return core.ErrOutOfBounds
+		}
+		for j := range data {
+			// mask is 0xff if j == i and 0x00 otherwise.
+			x := uint64(j ^ i)
+			mask := byte(0 - (((x - 1) &^ x) >> 63))
+			v |= data[j] & mask
+		}
+		return nil
+	})
+	return v, err
 }
 
 /*
-EqualTo performs a time-constant comparison on the contents of a LockedBuffer with a given buffer. A destroyed LockedBuffer will always return false.
+ConstantTimeCopyIf overwrites the data of a LockedBuffer with src if cond is 1, and leaves it unchanged if cond is 0. Exactly the same operations are performed in either case, so the condition is not revealed by a data-dependent branch or by the running time.
+
+ErrInvalidCondition is returned if cond is neither 0 nor 1, and ErrInvalidLength is returned if src is not the same size as the LockedBuffer. An error is also returned if the LockedBuffer is immutable or has been destroyed.
 */
-func (b *LockedBuffer) EqualTo(buf []byte) bool {
-	b.RLock()
-	defer b.RUnlock()
+func (b *LockedBuffer) ConstantTimeCopyIf(cond int, src []byte) error {
+	if cond != 0 && cond != 1 {
+		return ErrInvalidCondition
+	}
+	return b.Access(true, func(data []byte) error {
+		if len(data) != len(src) {
+			return ErrInvalidLength
+		}
+		subtle.ConstantTimeCopy(cond, data, src)
+		return nil
+	})
+}
+
+/*
+ContainsConstantTime reports whether needle appears anywhere within the data of a LockedBuffer. Every possible position is compared in full and the results are combined without branching, so the running time depends only on the sizes of the LockedBuffer and the needle and not on whether or where the needle appears. The cost is proportional to the product of the two sizes.
 
-	return core.Equal(b.Bytes(), buf)
+An empty needle is always found. An error is returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) ContainsConstantTime(needle []byte) (bool, error) {
+	found := 0
+	err := b.Access(false, func(data []byte) error {
+		if len(needle) == 0 {
+			found = 1
+			return nil
+		}
+		for i := 0; i+len(needle) <= len(data); i++ {
+			var v byte
+			for j := range needle {
+				v |= data[i+j] ^ needle[j]
+			}
+			found |= subtle.ConstantTimeByteEq(v, 0)
+		}
+		return nil
+	})
+	return found == 1, err
 }
 
 /*
 	Functions for representing the memory region as various data types.
 */
 
+/*
+WithPointer calls a given function with the address and length of a LockedBuffer's data, for passing it directly to a system call or to C code without copying it out of guarded memory. The memory is made readable for the duration of the call and its protection is restored afterwards.
+
+Guarded memory is allocated outside of the Go heap so the address never moves, and the LockedBuffer cannot be destroyed or have its protection changed until the function returns. The pointer must not be retained or written through after that. An empty LockedBuffer passes a nil pointer and a length of zero.
+
+core.ErrBufferExpired is returned without calling the function if the LockedBuffer has been destroyed. Otherwise the error returned by the function is forwarded.
+*/
+func (b *LockedBuffer) WithPointer(fn func(ptr unsafe.Pointer, n int) error) error {
+	return b.Access(false, func(data []byte) error {
+		if len(data) == 0 {
+			return fn(nil, 0)
+		}
+		return fn(unsafe.Pointer(&data[0]), len(data))
+	})
+}
+
 /*
 Bytes returns a byte slice referencing the protected region of memory.
+
+The slice can only be used while the memory is accessible. It causes an access violation after the memory has been made inaccessible with Protect or SetAutoProtect, in which case Access should be used instead.
 */
 func (b *LockedBuffer) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
 	return b.Buffer.Data()
 }
 
@@ -403,7 +1554,7 @@ If called on a destroyed LockedBuffer, a nil slice will be returned.
 func (b *LockedBuffer) Uint16() []uint16 {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -435,7 +1586,7 @@ If called on a destroyed LockedBuffer, a nil slice will be returned.
 func (b *LockedBuffer) Uint32() []uint32 {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -467,7 +1618,7 @@ If called on a destroyed LockedBuffer, a nil slice will be returned.
 func (b *LockedBuffer) Uint64() []uint64 {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -497,7 +1648,7 @@ Int8 returns a slice pointing to the protected region of memory with the data re
 func (b *LockedBuffer) Int8() []int8 {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -523,7 +1674,7 @@ If called on a destroyed LockedBuffer, a nil slice will be returned.
 func (b *LockedBuffer) Int16() []int16 {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -555,7 +1706,7 @@ If called on a destroyed LockedBuffer, a nil slice will be returned.
 func (b *LockedBuffer) Int32() []int32 {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -587,7 +1738,7 @@ If called on a destroyed LockedBuffer, a nil slice will be returned.
 func (b *LockedBuffer) Int64() []int64 {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -619,7 +1770,7 @@ The length of the buffer must be at least 8 bytes in size and the LockedBuffer s
 func (b *LockedBuffer) ByteArray8() *[8]byte {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -643,7 +1794,7 @@ The length of the buffer must be at least 16 bytes in size and the LockedBuffer
 func (b *LockedBuffer) ByteArray16() *[16]byte {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -667,7 +1818,7 @@ The length of the buffer must be at least 32 bytes in size and the LockedBuffer
 func (b *LockedBuffer) ByteArray32() *[32]byte {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
@@ -691,7 +1842,7 @@ The length of the buffer must be at least 64 bytes in size and the LockedBuffer
 func (b *LockedBuffer) ByteArray64() *[64]byte {
 
 	// Check if still alive.
-	if !b.Buffer.Alive() {
+	if !b.IsAlive() {
 		return nil
 	}
 
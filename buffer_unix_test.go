@@ -0,0 +1,63 @@
+// +build !windows
+
+package memguard
+
+import (
+	"syscall"
+	"testing"
+)
+
+// Returns the read end of a pipe that will produce the given data followed by EOF.
+func pipeWith(t *testing.T, data []byte) uintptr {
+	var p [2]int
+	if err := syscall.Pipe(p[:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syscall.Write(p[1], data); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Close(p[1])
+	return uintptr(p[0])
+}
+
+func TestNewBufferFromFd(t *testing.T) {
+	fd := pipeWith(t, []byte("yellow submarine"))
+	b, err := NewBufferFromFd(fd, 16)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("yellow submarine")) || b.IsMutable() {
+		t.Error("unexpected buffer", b.String())
+	}
+	b.Destroy()
+
+	// The descriptor is closed.
+	var stat syscall.Stat_t
+	if err := syscall.Fstat(int(fd), &stat); err != syscall.EBADF {
+		t.Error("expected descriptor to be closed; got", err)
+	}
+
+	// Only the requested length is read.
+	b, err = NewBufferFromFd(pipeWith(t, []byte("yellow submarine")), 6)
+	if err != nil || !equalTo(b, []byte("yellow")) {
+		t.Error("unexpected result", b.String(), err)
+	}
+	b.Destroy()
+
+	// Short reads are refused.
+	for _, data := range []string{"", "yellow"} {
+		fd := pipeWith(t, []byte(data))
+		b, err := NewBufferFromFd(fd, 16)
+		if err != ErrInvalidLength || b.IsAlive() {
+			t.Error("expected ErrInvalidLength; got", err)
+		}
+		if err := syscall.Fstat(int(fd), &stat); err != syscall.EBADF {
+			t.Error("expected descriptor to be closed; got", err)
+		}
+	}
+
+	b, err = NewBufferFromFd(pipeWith(t, nil), 0)
+	if err != nil || b.IsAlive() {
+		t.Error("expected null buffer; got", err)
+	}
+}
@@ -0,0 +1,57 @@
+package memguard
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+/*
+Cell holds a LockedBuffer that can be replaced atomically while other goroutines read it, such as a key that is rotated periodically. Readers never observe a partially replaced value, and a replaced LockedBuffer is only destroyed once every reader that acquired it has released it.
+
+The zero value is an empty Cell ready to use. A Cell is safe for concurrent use and must not be copied after first use.
+*/
+type Cell struct {
+	p unsafe.Pointer // *LockedBuffer
+}
+
+/*
+Load returns the LockedBuffer currently held by a Cell, or nil if it is empty. It may be replaced and destroyed at any moment, so it must be acquired before use; Acquire does both.
+*/
+func (c *Cell) Load() *LockedBuffer {
+	return (*LockedBuffer)(atomic.LoadPointer(&c.p))
+}
+
+/*
+Acquire returns the LockedBuffer currently held by a Cell after acquiring it with its Acquire method, so that it stays alive and usable until release is called even if it is replaced in the meantime. If the held LockedBuffer is replaced and destroyed between being loaded and acquired, the replacement is acquired instead.
+
+ok is false if the Cell is empty, or if the LockedBuffer it holds has been destroyed other than by Store.
+
+	b, release, ok := cell.Acquire()
+	if !ok {
+		return errNoKey
+	}
+	defer release()
+	// use b
+*/
+func (c *Cell) Acquire() (b *LockedBuffer, release func(), ok bool) {
+	for {
+		b = c.Load()
+		if release, ok = b.Acquire(); ok {
+			return b, release, true
+		}
+		if c.Load() == b {
+			return nil, release, false
+		}
+	}
+}
+
+/*
+Store atomically replaces the LockedBuffer held by a Cell. The previous LockedBuffer, if any, is destroyed as soon as every reader that acquired it has released it, so all readers either see the old value in full or the new one. Storing nil empties the Cell.
+
+The Cell takes ownership of the LockedBuffer, which should not be destroyed by the caller.
+*/
+func (c *Cell) Store(b *LockedBuffer) {
+	if old := (*LockedBuffer)(atomic.SwapPointer(&c.p, unsafe.Pointer(b))); old != nil && old != b {
+		old.Destroy()
+	}
+}
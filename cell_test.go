@@ -0,0 +1,92 @@
+package memguard
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestCell(t *testing.T) {
+	var c Cell
+	if c.Load() != nil {
+		t.Error("expected empty cell")
+	}
+	if b, _, ok := c.Acquire(); ok || b != nil {
+		t.Error("acquired from empty cell")
+	}
+
+	// The previous buffer is destroyed once its readers release it.
+	first := NewBufferFromBytes([]byte("first key"))
+	c.Store(first)
+	b, release, ok := c.Acquire()
+	if !ok || b != first {
+		t.Error("failed to acquire stored buffer")
+	}
+	second := NewBufferFromBytes([]byte("second key"))
+	c.Store(second)
+	if c.Load() != second {
+		t.Error("buffer was not replaced")
+	}
+	if !first.IsAlive() || !equalTo(first, []byte("first key")) {
+		t.Error("acquired buffer destroyed on replacement")
+	}
+	release()
+	if first.IsAlive() {
+		t.Error("replaced buffer not destroyed after release")
+	}
+
+	// Storing the same buffer again keeps it.
+	c.Store(second)
+	if !second.IsAlive() {
+		t.Error("buffer destroyed when stored again")
+	}
+
+	// Storing nil empties the cell.
+	c.Store(nil)
+	if c.Load() != nil || second.IsAlive() {
+		t.Error("cell not emptied")
+	}
+}
+
+func TestCellRotation(t *testing.T) {
+	var c Cell
+	c.Store(NewBufferFromBytes(bytes.Repeat([]byte{0}, 64)))
+	defer c.Store(nil)
+
+	// Readers check that every key they acquire is intact, while a writer rotates it.
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				b, release, ok := c.Acquire()
+				if !ok {
+					t.Error("failed to acquire key")
+					return
+				}
+				data := b.Bytes()
+				if len(data) != 64 || !bytes.Equal(data, bytes.Repeat(data[:1], 64)) {
+					t.Error("reader saw freed or torn key")
+				}
+				release()
+			}
+		}()
+	}
+
+	for i := 1; i <= 200; i++ {
+		c.Store(NewBufferFromBytes(bytes.Repeat([]byte{byte(i)}, 64)))
+	}
+	close(done)
+	wg.Wait()
+
+	if b := c.Load(); !equalTo(b, bytes.Repeat([]byte{200}, 64)) {
+		t.Error("unexpected final key", b.Bytes())
+	}
+}
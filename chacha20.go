@@ -0,0 +1,57 @@
+package memguard
+
+import (
+	"errors"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/chacha20"
+)
+
+// ErrInvalidNonceLength is returned when a nonce is not of a length supported by a cipher.
+var ErrInvalidNonceLength = errors.New("<memguard::ErrInvalidNonceLength> nonce must be 12 or 24 bytes")
+
+/*
+StreamXOR encrypts or decrypts the contents of a LockedBuffer in place by XORing them with the ChaCha20 keystream generated from a 32 byte key and a nonce, so that no separate ciphertext needs to be allocated. A 12 byte nonce selects ChaCha20 as defined in RFC 8439 and a 24 byte nonce selects XChaCha20.
+
+The keystream is the same every time a given key and nonce are used, so calling StreamXOR a second time with them decrypts the data. For the same reason a nonce must never be reused to encrypt different data under the same key. No authentication is provided, so StreamXOR should only be used where the ciphertext is protected against modification by other means; SealWithMetadata should be preferred otherwise.
+
+The key is used directly from guarded memory and the cipher state derived from it is wiped afterwards. An error is returned if the key is not 32 bytes long, if the nonce is not 12 or 24 bytes long, if data is immutable, or if either LockedBuffer has been destroyed.
+*/
+func (key *LockedBuffer) StreamXOR(data *LockedBuffer, nonce []byte) error {
+	if len(nonce) != chacha20.NonceSize && len(nonce) != chacha20.NonceSizeX {
+		return ErrInvalidNonceLength
+	}
+	return accessPair(key, false, data, true, func(k, d []byte) error {
+		if len(k) != chacha20.KeySize {
+			return core.ErrInvalidKeyLength
+		}
+		c, err := chacha20.NewUnauthenticatedCipher(k, nonce)
+		if err != nil {
+			return err
+		}
+		c.XORKeyStream(d, d)
+		*c = chacha20.Cipher{}
+		return nil
+	})
+}
+
+/*
+NewFromSeed deterministically expands a 32 byte seed held in a LockedBuffer into a new immutable LockedBuffer of a given length, using the ChaCha20 keystream keyed by the seed as a deterministic random bit generator. The same seed always yields the same output, so it can generate reproducible pads in tests or keystreams for stream ciphers, while both the seed and the output stay in guarded memory. The seed must be uniformly random and kept secret for the output to be unpredictable.
+
+A length of less than one returns a null buffer and core.ErrNullBuffer. An error is also returned if the seed is not 32 bytes long or has been destroyed.
+*/
+func NewFromSeed(seed *LockedBuffer, length int) (*LockedBuffer, error) {
+	if length < 1 {
+		return newNullBuffer(), core.ErrNullBuffer
+	}
+
+	// The keystream of a zero nonce over zeroed memory.
+	b := NewBuffer(length)
+	if err := seed.StreamXOR(b, make([]byte, chacha20.NonceSize)); err != nil {
+		b.Destroy()
+		return newNullBuffer(), err
+	}
+
+	b.Freeze()
+	return b, nil
+}
@@ -0,0 +1,144 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/chacha20"
+)
+
+func TestStreamXOR(t *testing.T) {
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+	plaintext := []byte("yellow submarine, purple submarine")
+
+	for _, size := range []int{chacha20.NonceSize, chacha20.NonceSizeX} {
+		nonce := make([]byte, size)
+		core.Scramble(nonce)
+
+		data := NewBuffer(len(plaintext))
+		data.Copy(plaintext)
+
+		// Encrypt in place.
+		if err := key.StreamXOR(data, nonce); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		expected := make([]byte, len(plaintext))
+		c, _ := chacha20.NewUnauthenticatedCipher(key.Bytes(), nonce)
+		c.XORKeyStream(expected, plaintext)
+		if !equalTo(data, expected) || bytes.Equal(expected, plaintext) {
+			t.Error("incorrect ciphertext; got", data.Bytes())
+		}
+
+		// Decrypt in place.
+		if err := key.StreamXOR(data, nonce); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if !equalTo(data, plaintext) {
+			t.Error("original not recovered; got", data.Bytes())
+		}
+		data.Destroy()
+	}
+
+	data := NewBuffer(16)
+	defer data.Destroy()
+	nonce := make([]byte, chacha20.NonceSize)
+
+	// Invalid nonce lengths.
+	for _, size := range []int{0, 8, 16, 32} {
+		if err := key.StreamXOR(data, make([]byte, size)); err != ErrInvalidNonceLength {
+			t.Error("expected ErrInvalidNonceLength; got", err)
+		}
+	}
+
+	// Invalid key length.
+	short := NewBufferRandom(16)
+	defer short.Destroy()
+	if err := short.StreamXOR(data, nonce); err != core.ErrInvalidKeyLength {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+
+	// Immutable data is left unchanged.
+	data.Freeze()
+	if err := key.StreamXOR(data, nonce); err != core.ErrBufferImmutable {
+		t.Error("expected ErrBufferImmutable; got", err)
+	}
+	if !equalTo(data, make([]byte, 16)) {
+		t.Error("immutable data was modified")
+	}
+
+	// Destroyed buffers.
+	d := NewBufferRandom(32)
+	d.Destroy()
+	if err := d.StreamXOR(data, nonce); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if err := key.StreamXOR(d, nonce); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestNewFromSeed(t *testing.T) {
+	seed := NewBufferRandom(32)
+	defer seed.Destroy()
+
+	a, err := NewFromSeed(seed, 1000)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	defer a.Destroy()
+	if a.Size() != 1000 || a.IsMutable() {
+		t.Error("invalid buffer")
+	}
+	if equalTo(a, make([]byte, 1000)) {
+		t.Error("output was not generated")
+	}
+
+	// The same seed yields the same output, and shorter outputs are prefixes.
+	b, err := NewFromSeed(seed, 1000)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	defer b.Destroy()
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("output is not deterministic")
+	}
+	c, _ := NewFromSeed(seed, 100)
+	defer c.Destroy()
+	if !bytes.Equal(c.Bytes(), a.Bytes()[:100]) {
+		t.Error("shorter output is not a prefix")
+	}
+
+	// Different seeds differ.
+	other := NewBufferRandom(32)
+	defer other.Destroy()
+	d, _ := NewFromSeed(other, 1000)
+	defer d.Destroy()
+	if bytes.Equal(a.Bytes(), d.Bytes()) {
+		t.Error("different seeds gave the same output")
+	}
+
+	// Known answer: the RFC 8439 keystream of the all-zero key and nonce.
+	zero := NewBuffer(32)
+	defer zero.Destroy()
+	z, _ := NewFromSeed(zero, 8)
+	defer z.Destroy()
+	if !bytes.Equal(z.Bytes(), []byte{0x76, 0xb8, 0xe0, 0xad, 0xa0, 0xf1, 0x3d, 0x90}) {
+		t.Error("unexpected keystream", z.Bytes())
+	}
+
+	// Invalid arguments.
+	if b, err := NewFromSeed(seed, 0); err != core.ErrNullBuffer || b.IsAlive() {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+	short := NewBufferRandom(16)
+	defer short.Destroy()
+	if b, err := NewFromSeed(short, 8); err != core.ErrInvalidKeyLength || b.IsAlive() {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+	short.Destroy()
+	if _, err := NewFromSeed(short, 8); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
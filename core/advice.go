@@ -0,0 +1,50 @@
+package core
+
+import (
+	"errors"
+)
+
+// Advice flags that can be passed to SetAdvice to describe how the memory of a Buffer should be treated.
+const (
+	AdviceDontDump = 1 << iota // Exclude the memory from core dumps
+	AdviceDoDump               // Include the memory in core dumps
+	AdviceWillNeed             // Expect the memory to be accessed soon
+	AdviceDontFork             // Do not make the memory available to child processes
+	AdviceDoFork               // Make the memory available to child processes
+)
+
+// Every valid advice flag.
+const adviceMask = AdviceDontDump | AdviceDoDump | AdviceWillNeed | AdviceDontFork | AdviceDoFork
+
+// ErrInvalidAdvice is returned when SetAdvice is given unknown or contradictory flags.
+var ErrInvalidAdvice = errors.New("<memguard::core::ErrInvalidAdvice> advice flags are unknown or contradictory")
+
+// ErrAdviceUnsupported is returned when SetAdvice is called on a platform that does not support it.
+var ErrAdviceUnsupported = errors.New("<memguard::core::ErrAdviceUnsupported> memory advice is not supported on this platform")
+
+// System call used to apply advice, replaceable in tests.
+var adviseMemory = madvise
+
+/*
+SetAdvice applies a combination of advice flags to the memory holding the data of a Buffer, overriding the defaults for that Buffer alone. Locking the memory of a Buffer also excludes it from core dumps where the platform supports it, so for example AdviceDoDump can be used for scratch memory that does not need this.
+
+Advice is applied with madvise(2) and is only supported on Linux; ErrAdviceUnsupported is returned elsewhere. ErrInvalidAdvice is returned if the flags contain unknown bits or both flags of a contradictory pair, and an error is returned if the Buffer has been destroyed.
+*/
+func (b *Buffer) SetAdvice(flags int) error {
+	if flags&^adviceMask != 0 ||
+		flags&(AdviceDontDump|AdviceDoDump) == AdviceDontDump|AdviceDoDump ||
+		flags&(AdviceDontFork|AdviceDoFork) == AdviceDontFork|AdviceDoFork {
+		return ErrInvalidAdvice
+	}
+
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check if destroyed.
+	if !b.alive {
+		return ErrBufferExpired
+	}
+
+	return adviseMemory(b.inner, flags)
+}
@@ -0,0 +1,27 @@
+// +build linux
+
+package core
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// Applies each of a set of advice flags to a page-aligned region of memory using madvise(2).
+func madvise(b []byte, flags int) error {
+	for _, a := range []struct {
+		flag, advice int
+	}{
+		{AdviceDontDump, unix.MADV_DONTDUMP},
+		{AdviceDoDump, unix.MADV_DODUMP},
+		{AdviceWillNeed, unix.MADV_WILLNEED},
+		{AdviceDontFork, unix.MADV_DONTFORK},
+		{AdviceDoFork, unix.MADV_DOFORK},
+	} {
+		if flags&a.flag != 0 {
+			if err := unix.Madvise(b, a.advice); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// +build linux
+
+package core
+
+import (
+	"testing"
+)
+
+func TestMadvise(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	defer b.Destroy()
+
+	for _, flags := range []int{AdviceDoDump, AdviceDontDump, AdviceWillNeed, AdviceDontFork | AdviceDoDump, AdviceDoFork, 0} {
+		if err := b.SetAdvice(flags); err != nil {
+			t.Error("unexpected error for flags", flags, "; got", err)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// +build !linux
+
+package core
+
+// Memory advice is only supported on Linux.
+func madvise(b []byte, flags int) error {
+	return ErrAdviceUnsupported
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestSetAdvice(t *testing.T) {
+	var calls []int
+	defer func(advise func([]byte, int) error) {
+		adviseMemory = advise
+	}(adviseMemory)
+	adviseMemory = func(b []byte, flags int) error {
+		calls = append(calls, flags)
+		return nil
+	}
+
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+
+	if err := b.SetAdvice(AdviceDoDump | AdviceWillNeed); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if len(calls) != 1 || calls[0] != AdviceDoDump|AdviceWillNeed {
+		t.Error("unexpected calls:", calls)
+	}
+
+	// Invalid flags are rejected before anything is applied.
+	for _, flags := range []int{
+		AdviceDontDump | AdviceDoDump,
+		AdviceDontFork | AdviceDoFork,
+		AdviceDoFork << 1,
+		-1,
+	} {
+		if err := b.SetAdvice(flags); err != ErrInvalidAdvice {
+			t.Error("expected ErrInvalidAdvice; got", err)
+		}
+	}
+	if len(calls) != 1 {
+		t.Error("advice applied for invalid flags:", calls)
+	}
+
+	b.Destroy()
+	if err := b.SetAdvice(AdviceDontDump); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if len(calls) != 1 {
+		t.Error("advice applied to destroyed buffer:", calls)
+	}
+}
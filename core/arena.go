@@ -0,0 +1,201 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"unsafe"
+
+	"github.com/awnumar/memcall"
+)
+
+// ErrArenaFull is returned when an Arena does not have enough free memory to hold a requested Buffer.
+var ErrArenaFull = errors.New("<memguard::core::ErrArenaFull> arena does not have enough free memory")
+
+// ErrArenaExpired is returned when attempting to allocate from an Arena that has been destroyed.
+var ErrArenaExpired = errors.New("<memguard::core::ErrArenaExpired> arena has been destroyed and can no longer be used")
+
+/*
+Arena is a fixed region of locked memory from which Buffers can be allocated. The whole region is locked when the Arena is created, so the total amount of memory locked on its behalf is bounded by its size and no further locking is required for each allocation.
+
+Each Buffer allocated from an Arena occupies its own guard pages and inner pages, exactly as a Buffer returned by NewBuffer, and its pages are returned to the Arena when it is destroyed.
+*/
+type Arena struct {
+	sync.Mutex
+
+	alive  bool   // Signals that destruction has not come
+	memory []byte // Entire allocated memory region
+	used   []bool // Allocation state of each page
+}
+
+/*
+NewArena allocates and locks an Arena of at least a given number of bytes, which is rounded up to a multiple of the system page size.
+*/
+func NewArena(size int) (*Arena, error) {
+	if size < 1 {
+		return nil, ErrNullBuffer
+	}
+
+	a := new(Arena)
+	memory, err := memcall.Alloc(roundToPageSize(size))
+	if err != nil {
+		return nil, err
+	}
+	if err := memcall.Lock(memory); err != nil {
+		memcall.Free(memory)
+		return nil, err
+	}
+	addLockedBytes(len(memory))
+
+	// Pages are only accessible while they belong to a Buffer.
+	if err := memcall.Protect(memory, memcall.NoAccess()); err != nil {
+		Panic(err)
+	}
+
+	a.alive = true
+	a.memory = memory
+	a.used = make([]bool, len(memory)/pageSize)
+	return a, nil
+}
+
+/*
+NewBuffer allocates a Buffer of a given size from an Arena. It requires two guard pages plus enough pages to hold the data, which must be free and adjacent within the Arena, otherwise ErrArenaFull is returned.
+*/
+func (a *Arena) NewBuffer(size int) (*Buffer, error) {
+	if size < 1 {
+		return nil, ErrNullBuffer
+	}
+
+	// Attain lock.
+	a.Lock()
+	defer a.Unlock()
+
+	// Check if destroyed.
+	if !a.alive {
+		return nil, ErrArenaExpired
+	}
+
+	// Find the first run of free pages that is long enough.
+	pages := 2 + roundToPageSize(size)/pageSize
+	start, run := 0, 0
+	for i := 0; i < len(a.used) && run < pages; i++ {
+		if a.used[i] {
+			start, run = i+1, 0
+		} else {
+			run++
+		}
+	}
+	if run < pages {
+		return nil, ErrArenaFull
+	}
+
+	// Construct the Buffer over the pages.
+	b := new(Buffer)
+	b.memory = getBytes(&a.memory[start*pageSize], pages*pageSize)
+	if err := memcall.Protect(b.memory, memcall.ReadWrite()); err != nil {
+		return nil, err
+	}
+	b.layout(size)
+
+	// Generate the canary value, giving up if no random bytes are available.
+	if err := Scramble(b.canary); err != nil {
+		if err := memcall.Protect(b.memory, memcall.NoAccess()); err != nil {
+			Panic(err)
+		}
+		return nil, err
+	}
+
+	for i := start; i < start+pages; i++ {
+		a.used[i] = true
+	}
+	b.arena = a
+	b.activate()
+
+	return b, nil
+}
+
+// Returns the pages of a destroyed Buffer to the Arena. The memory must already have been wiped.
+func (a *Arena) release(memory []byte) error {
+	// Attain lock.
+	a.Lock()
+	defer a.Unlock()
+
+	if err := memcall.Protect(memory, memcall.NoAccess()); err != nil {
+		return err
+	}
+
+	start := int(uintptr(unsafe.Pointer(&memory[0])) - uintptr(unsafe.Pointer(&a.memory[0])))
+	for i := start / pageSize; i < (start+len(memory))/pageSize; i++ {
+		a.used[i] = false
+	}
+	return nil
+}
+
+/*
+Destroy destroys every Buffer that was allocated from an Arena before wiping, unlocking, and freeing its memory. The Arena can no longer be used afterwards.
+*/
+func (a *Arena) Destroy() {
+	if err := a.destroy(); err != nil {
+		Panic(err)
+	}
+}
+
+func (a *Arena) destroy() error {
+	// Prevent any further allocations.
+	a.Lock()
+	if !a.alive {
+		a.Unlock()
+		return nil
+	}
+	a.alive = false
+	a.Unlock()
+
+	// Destroy the Buffers allocated from the Arena.
+	for _, b := range buffers.copy() {
+		if b.allocatedFrom(a) {
+			b.Destroy()
+		}
+	}
+
+	// Attain lock.
+	a.Lock()
+	defer a.Unlock()
+
+	// Wipe and free the memory.
+	if err := memcall.Protect(a.memory, memcall.ReadWrite()); err != nil {
+		return err
+	}
+	Wipe(a.memory)
+	if err := memcall.Unlock(a.memory); err != nil {
+		return err
+	}
+	addLockedBytes(-len(a.memory))
+	if err := memcall.Free(a.memory); err != nil {
+		return err
+	}
+
+	a.memory = nil
+	a.used = nil
+	return nil
+}
+
+// Free returns the number of bytes in an Arena that do not belong to any Buffer.
+func (a *Arena) Free() int {
+	a.Lock()
+	defer a.Unlock()
+
+	var free int
+	for _, used := range a.used {
+		if !used {
+			free += pageSize
+		}
+	}
+	return free
+}
+
+// Reports whether a Buffer was allocated from a given Arena.
+func (b *Buffer) allocatedFrom(a *Arena) bool {
+	b.RLock()
+	defer b.RUnlock()
+
+	return b.arena == a
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewArena(t *testing.T) {
+	if _, err := NewArena(0); err != ErrNullBuffer {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+
+	a, err := NewArena(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(a.memory) != pageSize || len(a.used) != 1 {
+		t.Error("arena size not rounded to a page")
+	}
+	if a.Free() != pageSize {
+		t.Error("unexpected free space", a.Free())
+	}
+	a.Destroy()
+}
+
+func TestArenaNewBuffer(t *testing.T) {
+	a, err := NewArena(11 * pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	live := atomic.LoadInt64(&lockedBytes)
+
+	// Allocate until full.
+	var bufs []*Buffer
+	for {
+		b, err := a.NewBuffer(2 * pageSize)
+		if err == ErrArenaFull {
+			break
+		}
+		if err != nil {
+			t.Error(err)
+			break
+		}
+		bufs = append(bufs, b)
+	}
+	if len(bufs) != 2 {
+		t.Error("expected two buffers of four pages; got", len(bufs))
+	}
+	if a.Free() != 3*pageSize {
+		t.Error("unexpected free space", a.Free())
+	}
+	if atomic.LoadInt64(&lockedBytes) != live {
+		t.Error("arena allocations changed total of locked memory")
+	}
+
+	// Buffers are independent of each other.
+	Scramble(bufs[0].Data())
+	Wipe(bufs[1].Data())
+	if bytes.Equal(bufs[0].Data(), bufs[1].Data()) {
+		t.Error("buffers share memory")
+	}
+	if !buffers.exists(bufs[0]) || !buffers.exists(bufs[1]) {
+		t.Error("buffers not in list")
+	}
+
+	// A small buffer fits into the remaining space, a larger one does not.
+	if _, err := a.NewBuffer(2 * pageSize); err != ErrArenaFull {
+		t.Error("expected ErrArenaFull; got", err)
+	}
+	small, err := a.NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Destroying a buffer returns its pages to the arena.
+	bufs[0].Destroy()
+	if a.Free() != 4*pageSize {
+		t.Error("unexpected free space", a.Free())
+	}
+	if bufs[0].Alive() || bufs[0].arena != nil {
+		t.Error("buffer not destroyed")
+	}
+	b, err := a.NewBuffer(pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(b.Data(), make([]byte, pageSize)) {
+		t.Error("reused memory not wiped")
+	}
+
+	// Destroying the arena destroys all of its buffers.
+	a.Destroy()
+	if b.Alive() || small.Alive() || bufs[1].Alive() {
+		t.Error("buffers not destroyed with arena")
+	}
+	if buffers.exists(b) {
+		t.Error("buffer still in list")
+	}
+	if atomic.LoadInt64(&lockedBytes) != live-int64(11*pageSize) {
+		t.Error("arena memory not unlocked")
+	}
+	if _, err := a.NewBuffer(32); err != ErrArenaExpired {
+		t.Error("expected ErrArenaExpired; got", err)
+	}
+	a.Destroy() // idempotent
+}
+
+func TestArenaGrow(t *testing.T) {
+	a, err := NewArena(3 * pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	defer a.Destroy()
+
+	b, err := a.NewBuffer(8)
+	if err != nil {
+		t.Error(err)
+	}
+	copy(b.Data(), "01234567")
+
+	// Growing moves the buffer out of the arena.
+	if err := b.Grow(8); err != nil {
+		t.Error(err)
+	}
+	if b.arena != nil || a.Free() != 3*pageSize {
+		t.Error("memory not returned to arena")
+	}
+	if !bytes.Equal(b.Data()[:8], []byte("01234567")) {
+		t.Error("data not preserved")
+	}
+	b.Destroy()
+}
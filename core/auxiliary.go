@@ -18,6 +18,10 @@ func roundToPageSize(length int) int {
 
 // Convert a pointer and length to a byte slice that describes that memory.
 func getBytes(ptr *byte, len int) []byte {
-	var sl = reflect.SliceHeader{Data: uintptr(unsafe.Pointer(ptr)), Len: len, Cap: len}
-	return *(*[]byte)(unsafe.Pointer(&sl))
+	var sl []byte
+	sh := (*reflect.SliceHeader)(unsafe.Pointer(&sl))
+	sh.Data = uintptr(unsafe.Pointer(ptr))
+	sh.Len = len
+	sh.Cap = len
+	return sl
 }
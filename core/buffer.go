@@ -2,13 +2,34 @@ package core
 
 import (
 	"errors"
+	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/awnumar/memcall"
+	"golang.org/x/crypto/blake2b"
 )
 
 var (
 	buffers = new(bufferList)
+
+	lockedBytes     int64 // Total size of memory currently locked by Buffers
+	peakLockedBytes int64 // Highest value lockedBytes has reached
+
+	destroyedBuffers int64 // Number of Buffers that have been destroyed
+	destroyNanos     int64 // Total time spent wiping and freeing destroyed Buffers
+
+	// System calls used to manage the memory of individually allocated Buffers, replaceable in tests.
+	allocMemory   = memcall.Alloc
+	lockMemory    = memcall.Lock
+	unlockMemory  = memcall.Unlock
+	freeMemory    = memcall.Free
+	protectMemory = memcall.Protect
+
+	// Reports whether memory is resident, replaceable in tests.
+	residentMemory = resident
 )
 
 // ErrNullBuffer is returned when attempting to construct a buffer of size less than one.
@@ -17,6 +38,27 @@ var ErrNullBuffer = errors.New("<memguard::core::ErrNullBuffer> buffer size must
 // ErrBufferExpired is returned when attempting to perform an operation on or with a buffer that has been destroyed.
 var ErrBufferExpired = errors.New("<memguard::core::ErrBufferExpired> buffer has been purged from memory and can no longer be used")
 
+// ErrBufferImmutable is returned when attempting to modify the contents of a buffer that has been made immutable.
+var ErrBufferImmutable = errors.New("<memguard::core::ErrBufferImmutable> buffer is immutable and cannot be modified")
+
+// ErrImmutable is returned when attempting to make a buffer mutable after it has been permanently frozen with FreezeImmutable.
+var ErrImmutable = errors.New("<memguard::core::ErrImmutable> buffer has been permanently frozen and cannot be made mutable")
+
+// ErrLockTimeout is returned when the memory for a buffer could not be locked within the time allowed.
+var ErrLockTimeout = errors.New("<memguard::core::ErrLockTimeout> timed out waiting for memory to be locked")
+
+// ErrOutOfBounds is returned when an offset or length falls outside of the data region of a buffer.
+var ErrOutOfBounds = errors.New("<memguard::core::ErrOutOfBounds> offset or length is out of bounds")
+
+// ErrCanaryCorrupted is the value passed to Panic when a Buffer's canary is found to have been modified while verification on access is enabled.
+var ErrCanaryCorrupted = errors.New("<memguard::core::ErrCanaryCorrupted> canary verification failed; memory corruption detected")
+
+// ErrNotResident is returned when the memory backing a Buffer is not resident after it has been touched.
+var ErrNotResident = errors.New("<memguard::core::ErrNotResident> memory is not resident")
+
+// ErrCompactUnsupported is returned when compacting the residency of a Buffer whose memory is shared or belongs to an Arena.
+var ErrCompactUnsupported = errors.New("<memguard::core::ErrCompactUnsupported> residency of shared or arena memory cannot be compacted")
+
 /*
 Buffer is a structure that holds raw sensitive data.
 
@@ -25,8 +67,17 @@ The number of Buffers that can exist at one time is limited by how much memory y
 type Buffer struct {
 	sync.RWMutex // Local mutex lock
 
-	alive   bool // Signals that destruction has not come
-	mutable bool // Mutability state of underlying memory
+	alive       bool // Signals that destruction has not come
+	mutable     bool // Mutability state of underlying memory
+	noaccess    bool // Signals that the data pages are inaccessible
+	autoProtect bool // Signals that the data pages are kept inaccessible between accesses
+	permanent   bool // Signals that mutability can never be restored
+	dirty       bool // Signals that the data may have been modified
+	retired     bool // Signals that the Buffer is to be destroyed once every acquisition is released
+
+	readers int // Number of outstanding acquisitions
+
+	onDestroy []func() // Functions to call once the Buffer has been destroyed
 
 	data   []byte // Portion of memory holding the data
 	memory []byte // Entire allocated memory region
@@ -35,13 +86,63 @@ type Buffer struct {
 	inner     []byte // Inner region between the guard pages
 	postguard []byte // Guard page addressed after the data
 
-	canary []byte // Value written behind data to detect spillage
+	canary    []byte   // Value written behind data to detect spillage
+	canarySum [32]byte // Digest of the canary, checked on access if enabled
+
+	arena *Arena // Arena the memory was allocated from, if any
+
+	shared   bool     // Signals that the inner region is shared with other processes
+	file     *os.File // Shared memory file owned by the Buffer, if any
+	unlocked bool     // Signals that the memory was not locked
+}
+
+/*
+BufferOptions configures how the memory of a Buffer is allocated by NewBufferWithOptions. The zero value gives the same Buffer as NewBuffer.
+*/
+type BufferOptions struct {
+	// Timeout limits how long locking the memory may take, if positive. See NewBufferWithTimeout.
+	Timeout time.Duration
+
+	// Bind requests that the memory is bound to the NUMA node Node before it is locked. See NewBufferOnNode.
+	Bind bool
+	Node int
+
+	// NoLock leaves the memory unlocked, so that it may be swapped to disk. The guard pages and canary are unaffected. This is only intended for environments in which memory cannot be locked at all.
+	NoLock bool
 }
 
 /*
-NewBuffer is a raw constructor for the Buffer object.
+NewBuffer is a raw constructor for the Buffer object. An error is returned if the size is less than one or if random bytes for the canary could not be read from RandReader.
 */
 func NewBuffer(size int) (*Buffer, error) {
+	return NewBufferWithOptions(size, BufferOptions{})
+}
+
+/*
+NewBufferWithTimeout is identical to NewBuffer except that ErrLockTimeout is returned if locking the memory takes longer than a given duration, instead of waiting for it indefinitely. Any other failure to lock the memory is also returned as an error rather than causing a panic.
+
+The memory is allocated before the attempt to lock it, so a timeout does not mean that the allocation failed. If the lock eventually completes after the timeout, the memory is unlocked and freed in the background. A timeout of zero or less has already expired, so ErrLockTimeout is returned straight away without allocating anything; use NewBuffer to wait indefinitely.
+*/
+func NewBufferWithTimeout(size int, timeout time.Duration) (*Buffer, error) {
+	if size >= 1 && timeout <= 0 {
+		return nil, ErrLockTimeout
+	}
+	return NewBufferWithOptions(size, BufferOptions{Timeout: timeout})
+}
+
+/*
+NewBufferOnNode is identical to NewBuffer except that the memory holding the data is bound to a given NUMA node before it is locked. This is only supported on Linux.
+
+If the binding cannot be applied, for example because the system has no NUMA support or the node does not exist, a warning is logged and the Buffer is allocated with the default memory policy instead. The layout of the guard pages is unaffected.
+*/
+func NewBufferOnNode(size, node int) (*Buffer, error) {
+	return NewBufferWithOptions(size, BufferOptions{Bind: true, Node: node})
+}
+
+/*
+NewBufferWithOptions is identical to NewBuffer except that the allocation is configured by a given set of options. Options can be combined freely, although a timeout has no effect on memory that is not locked.
+*/
+func NewBufferWithOptions(size int, opts BufferOptions) (*Buffer, error) {
 	var err error
 
 	// Return an error if length < 1.
@@ -53,11 +154,125 @@ func NewBuffer(size int) (*Buffer, error) {
 	b := new(Buffer)
 
 	// Allocate the total needed memory
-	innerLen := roundToPageSize(size)
-	b.memory, err = memcall.Alloc((2 * pageSize) + innerLen)
+	b.memory, err = allocMemory((2 * pageSize) + roundToPageSize(size))
 	if err != nil {
 		Panic(err)
 	}
+	b.layout(size)
+
+	// Apply any memory policy before the pages are locked.
+	if opts.Bind {
+		if err := bindToNode(b.inner, opts.Node); err != nil {
+			warnf("failed to bind memory at address %p to NUMA node %d: %s", &b.inner[0], opts.Node, err)
+		}
+	}
+
+	// Generate the canary value, giving up if no random bytes are available.
+	if err := Scramble(b.canary); err != nil {
+		if err := freeMemory(b.memory); err != nil {
+			Panic(err)
+		}
+		return nil, err
+	}
+
+	// Lock the pages that will hold sensitive data.
+	if opts.NoLock {
+		b.unlocked = true
+	} else if opts.Timeout > 0 {
+		if err := lockWithTimeout(b.memory, b.inner, opts.Timeout); err != nil {
+			if err != ErrLockTimeout {
+				if err := freeMemory(b.memory); err != nil {
+					Panic(err)
+				}
+			}
+			return nil, err
+		}
+	} else if err := lockMemory(b.inner); err != nil {
+		Panic(err)
+	}
+	if !b.unlocked {
+		// Check that locking the memory was effective, if requested.
+		if atomic.LoadInt32(&verifyLockOnCreate) == 1 {
+			ok, err := residentMemory(b.inner)
+			if err == nil && !ok {
+				err = ErrLockIneffective
+			}
+			if err != nil {
+				if err := unlockMemory(b.inner); err != nil {
+					Panic(err)
+				}
+				if err := freeMemory(b.memory); err != nil {
+					Panic(err)
+				}
+				return nil, err
+			}
+		}
+		addLockedBytes(len(b.inner))
+	}
+
+	// Protect the memory and make the Buffer available.
+	b.activate()
+
+	// Return the created Buffer to the caller.
+	return b, nil
+}
+
+// ErrLockIneffective is returned when the memory of a new Buffer is not resident after it has been locked, and verification of this has been enabled with SetVerifyLockOnCreate.
+var ErrLockIneffective = errors.New("<memguard::core::ErrLockIneffective> memory is not resident after being locked")
+
+// Set to 1 if the memory of every new Buffer should be checked to be resident after it has been locked.
+var verifyLockOnCreate int32
+
+/*
+SetVerifyLockOnCreate sets whether the memory of every new Buffer is checked to be resident immediately after it has been locked. Locking memory should fault in every page, so memory that is not resident indicates that locking it had no effect, as can happen in some misconfigured containers. The Buffer is then freed and ErrLockIneffective is returned instead of silently proceeding with memory that could be swapped out.
+
+Verification is disabled by default. Residency can only be checked on Linux; elsewhere the check always passes. Buffers allocated with BufferOptions.NoLock are not checked.
+*/
+func SetVerifyLockOnCreate(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&verifyLockOnCreate, v)
+}
+
+// Locks the inner region of some memory, giving up after a timeout. The memory is unlocked and freed once the lock completes if it has timed out.
+func lockWithTimeout(memory, inner []byte, timeout time.Duration) error {
+	result := make(chan error)
+	timedOut := make(chan struct{})
+
+	go func() {
+		err := lockMemory(inner)
+		select {
+		case result <- err:
+		case <-timedOut:
+			// Nobody is waiting, so clean up.
+			if err == nil {
+				if err := unlockMemory(inner); err != nil {
+					warnf("failed to unlock memory at address %p after timeout: %s", &inner[0], err)
+				}
+			}
+			if err := freeMemory(memory); err != nil {
+				warnf("failed to free memory at address %p after timeout: %s", &memory[0], err)
+			}
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		close(timedOut)
+		return ErrLockTimeout
+	}
+}
+
+// Constructs the slice references into a Buffer's memory for a given size of data.
+func (b *Buffer) layout(size int) {
+	innerLen := len(b.memory) - (2 * pageSize)
 
 	// Construct slice reference for data buffer.
 	b.data = getBytes(&b.memory[pageSize+innerLen-size], size)
@@ -69,18 +284,14 @@ func NewBuffer(size int) (*Buffer, error) {
 
 	// Construct slice reference for canary portion of inner page.
 	b.canary = getBytes(&b.memory[pageSize], len(b.inner)-len(b.data))
+}
 
-	// Lock the pages that will hold sensitive data.
-	if err := memcall.Lock(b.inner); err != nil {
-		Panic(err)
-	}
-
-	// Initialise the canary value and reference regions.
-	if err := Scramble(b.canary); err != nil {
-		Panic(err)
-	}
+// Copies an initialised canary to the guard pages, protects them, and adds the Buffer to the list of active buffers.
+func (b *Buffer) activate() {
+	// Initialise the canary reference regions.
 	Copy(b.preguard, b.canary)
 	Copy(b.postguard, b.canary)
+	b.canarySum = blake2b.Sum256(b.canary)
 
 	// Make the guard pages inaccessible.
 	if err := memcall.Protect(b.preguard, memcall.NoAccess()); err != nil {
@@ -96,9 +307,6 @@ func NewBuffer(size int) (*Buffer, error) {
 
 	// Append the container to list of active buffers.
 	buffers.add(b)
-
-	// Return the created Buffer to the caller.
-	return b, nil
 }
 
 // Data returns a byte slice representing the memory region containing the data.
@@ -128,25 +336,49 @@ func (b *Buffer) freeze() error {
 		return nil
 	}
 
-	// Only do anything if currently mutable.
-	if b.mutable {
-		// Make the memory immutable.
-		if err := memcall.Protect(b.inner, memcall.ReadOnly()); err != nil {
-			return err
-		}
-		b.mutable = false
+	// Make the memory immutable.
+	return b.protect(!b.autoProtect, false)
+}
+
+/*
+FreezeImmutable permanently makes the underlying memory of a given buffer immutable. Any subsequent attempt to make it mutable will fail: Melt does nothing, and TryMelt and Protect return ErrImmutable, so the only remaining way to change its contents is to destroy it. Inaccessible memory stays inaccessible.
+
+An error is returned if the Buffer has been destroyed.
+*/
+func (b *Buffer) FreezeImmutable() error {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check if destroyed.
+	if !b.alive {
+		return ErrBufferExpired
 	}
 
+	// Make the memory immutable for good.
+	if err := b.protect(!b.noaccess, false); err != nil {
+		return err
+	}
+	b.permanent = true
 	return nil
 }
 
-// Melt makes the underlying memory of a given buffer mutable. This will do nothing if the Buffer has been destroyed.
+// Melt makes the underlying memory of a given buffer mutable. This will do nothing if the Buffer has been destroyed, or if it has been frozen with FreezeImmutable; see TryMelt.
 func (b *Buffer) Melt() {
-	if err := b.melt(); err != nil {
+	if err := b.melt(); err != nil && err != ErrImmutable {
 		Panic(err)
 	}
 }
 
+// TryMelt is identical to Melt except that ErrImmutable is returned if the Buffer has been frozen with FreezeImmutable.
+func (b *Buffer) TryMelt() error {
+	err := b.melt()
+	if err != nil && err != ErrImmutable {
+		Panic(err)
+	}
+	return err
+}
+
 func (b *Buffer) melt() error {
 	// Attain lock.
 	b.Lock()
@@ -157,17 +389,251 @@ func (b *Buffer) melt() error {
 		return nil
 	}
 
-	// Only do anything if currently immutable.
-	if !b.mutable {
-		// Make the memory mutable.
-		if err := memcall.Protect(b.inner, memcall.ReadWrite()); err != nil {
+	// Refuse if permanently frozen.
+	if b.permanent {
+		return ErrImmutable
+	}
+
+	// Make the memory mutable.
+	return b.protect(!b.autoProtect, true)
+}
+
+/*
+Touch reads a byte from every page of a Buffer's inner region, forcing any pages that have not yet been backed by physical memory to be faulted in, and then verifies that they are resident. The contents of the Buffer are not modified.
+
+This allows latency-sensitive code to take any page faults up front rather than in the middle of an operation. An error is returned if the Buffer has been destroyed, and ErrNotResident is returned if some pages are still not resident afterwards. Residency can only be verified on Linux.
+*/
+func (b *Buffer) Touch() error {
+	return b.Access(false, func(data []byte) error {
+		var sum byte
+		for i := 0; i < len(b.inner); i += pageSize {
+			sum |= b.inner[i]
+		}
+		runtime.KeepAlive(sum)
+
+		ok, err := resident(b.inner)
+		if err != nil {
 			return err
 		}
-		b.mutable = true
+		if !ok {
+			return ErrNotResident
+		}
+		return nil
+	})
+}
+
+/*
+CompactResidency releases the physical memory backing the pages of a Buffer's data that lie entirely beyond the first usedLen bytes, for Buffers of which only a prefix is in use. The Buffer keeps its full length, and the released region reads as zeroes, its previous contents being discarded. Released pages are locked again as they are faulted back in by being accessed, using mlock2(2) with MLOCK_ONFAULT.
+
+This weakens the guarantees for the released region only: its memory is no longer reserved, so faulting it back in may fail under memory pressure like any other allocation. The pages holding the first usedLen bytes and the canary are unaffected.
+
+ErrOutOfBounds is returned if usedLen is negative or larger than the data, and ErrCompactUnsupported is returned for shared Buffers and Buffers allocated from an Arena. An error is also returned if the Buffer is immutable or has been destroyed. Compacting is only supported on Linux, and ErrAdviceUnsupported is returned elsewhere. If the pages could not be locked on fault, which requires Linux 4.4, they are locked immediately as usual and the error is returned.
+*/
+func (b *Buffer) CompactResidency(usedLen int) error {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check the state of the buffer.
+	if !b.alive {
+		return ErrBufferExpired
+	}
+	if !b.mutable {
+		return ErrBufferImmutable
+	}
+	if usedLen < 0 || usedLen > len(b.data) {
+		return ErrOutOfBounds
+	}
+	if b.shared || b.arena != nil {
+		return ErrCompactUnsupported
+	}
+
+	// The data ends at the end of the inner region, so release every page after the one containing its last used byte.
+	start := roundToPageSize(len(b.inner) - len(b.data) + usedLen)
+	if start >= len(b.inner) {
+		return nil
+	}
+	b.dirty = true
+	return releasePages(b.inner[start:], !b.unlocked)
+}
+
+/*
+Protect sets the protection of the memory holding a Buffer's data. There are four combinations:
+
+	read   write
+	true   true    readable and writable, equivalent to Melt
+	true   false   read-only, equivalent to Freeze
+	false  true    inaccessible, modifications through Access are permitted
+	false  false   inaccessible, modifications through Access are refused
+
+Memory cannot be made writable without also being readable, so when read is false the write flag only controls whether the data may be modified through Access, which temporarily relaxes the protection for the duration of the call. Directly accessing the data of an inaccessible Buffer will cause an access violation.
+
+An error is returned if the Buffer has been destroyed, or if write is true and the Buffer has been frozen with FreezeImmutable.
+*/
+func (b *Buffer) Protect(read, write bool) error {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check if destroyed.
+	if !b.alive {
+		return ErrBufferExpired
+	}
+
+	// Refuse if permanently frozen.
+	if write && b.permanent {
+		return ErrImmutable
+	}
+
+	return b.protect(read && !b.autoProtect, write)
+}
+
+/*
+SetAutoProtect sets whether the data pages of a Buffer are kept inaccessible between accesses. While enabled the memory is made inaccessible straight away and stays that way, except for the duration of each call to Access, so that reading or writing the slice returned by Data causes an access violation. Freeze, Melt and Protect then only control whether Access may modify the data. Disabling it makes the memory readable again.
+
+An error is returned if the Buffer has been destroyed.
+*/
+func (b *Buffer) SetAutoProtect(enabled bool) error {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check if destroyed.
+	if !b.alive {
+		return ErrBufferExpired
+	}
+
+	if err := b.protect(!enabled, b.mutable); err != nil {
+		return err
+	}
+	b.autoProtect = enabled
+	return nil
+}
+
+// Sets the protection state; the caller must hold the write lock.
+func (b *Buffer) protect(read, write bool) error {
+	// Only do anything if the state differs.
+	if b.noaccess == !read && b.mutable == write {
+		return nil
+	}
+
+	flag := memcall.ReadOnly()
+	if !read {
+		flag = memcall.NoAccess()
+	} else if write {
+		flag = memcall.ReadWrite()
 	}
+	if err := protectMemory(b.inner, flag); err != nil {
+		return err
+	}
+
+	b.noaccess = !read
+	b.mutable = write
 	return nil
 }
 
+/*
+Access calls a given function with the data of a Buffer. If the memory is currently inaccessible, it is made readable (or writable if write is true) for the duration of the call and its protection is restored afterwards, even if the function panics. The function must not retain the slice.
+
+An error is returned if the Buffer has been destroyed, or if write is true and the Buffer is immutable. Otherwise the error returned by the function is forwarded.
+
+Readers of accessible memory share a read lock and do not alter its protection.
+*/
+func (b *Buffer) Access(write bool, fn func(data []byte) error) error {
+	if atomic.LoadInt32(&verifyOnAccess) == 0 {
+		return b.access(write, fn)
+	}
+
+	// Verify the canary before running the function. Panic purges every Buffer, so the lock must be released first.
+	corrupted := false
+	err := b.access(write, func(data []byte) error {
+		if blake2b.Sum256(b.canary) != b.canarySum {
+			corrupted = true
+			return ErrCanaryCorrupted
+		}
+		return fn(data)
+	})
+	if corrupted {
+		Panic(ErrCanaryCorrupted)
+	}
+	return err
+}
+
+// Set to 1 if every access to a Buffer should first verify its canary.
+var verifyOnAccess int32
+
+/*
+SetVerifyCanaryOnAccess sets whether Access, and so every operation built on it, first verifies that the canary in front of a Buffer's data is intact. Corruption caused by writing before the start of the data is then caught at the next access, rather than only when the Buffer is destroyed. If the canary has been modified, Panic is called with ErrCanaryCorrupted.
+
+Verification is disabled by default. It hashes the canary, which is up to a page long, on every access, so it trades performance for earlier detection.
+*/
+func SetVerifyCanaryOnAccess(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&verifyOnAccess, v)
+}
+
+func (b *Buffer) access(write bool, fn func(data []byte) error) (err error) {
+	// Readable memory does not need its protection changed.
+	if !write {
+		b.RLock()
+		if b.alive && !b.noaccess {
+			defer b.RUnlock()
+			return fn(b.data)
+		}
+		b.RUnlock()
+	}
+
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check the state of the buffer.
+	if !b.alive {
+		return ErrBufferExpired
+	}
+	if write && !b.mutable {
+		return ErrBufferImmutable
+	}
+	if write {
+		b.dirty = true
+	}
+
+	// Relax the protection for the duration of the call.
+	restore, err := b.relax(write)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := restore(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	return fn(b.data)
+}
+
+// Grants access to inaccessible data pages, returning a function that restores their protection. The caller must hold the write lock.
+func (b *Buffer) relax(write bool) (func() error, error) {
+	if !b.noaccess {
+		return func() error { return nil }, nil
+	}
+
+	flag := memcall.ReadOnly()
+	if write {
+		flag = memcall.ReadWrite()
+	}
+	if err := protectMemory(b.inner, flag); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return protectMemory(b.inner, memcall.NoAccess())
+	}, nil
+}
+
 // Scramble attempts to overwrite the data with cryptographically-secure random bytes.
 func (b *Buffer) Scramble() {
 	if err := b.scramble(); err != nil {
@@ -182,6 +648,34 @@ func (b *Buffer) scramble() error {
 	return Scramble(b.Data())
 }
 
+/*
+Clear overwrites the data of a Buffer with zeros in place, leaving it allocated and usable. If the memory has been frozen or made inaccessible, it is made writable for the duration of the wipe and its protection is restored afterwards.
+
+An error is returned if the Buffer has been destroyed, and ErrImmutable is returned if it has been frozen with FreezeImmutable, in which case it is left unchanged.
+*/
+func (b *Buffer) Clear() error {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check the state of the buffer.
+	if !b.alive {
+		return ErrBufferExpired
+	}
+	if b.permanent {
+		return ErrImmutable
+	}
+
+	// Temporarily make the memory writable.
+	read, write := !b.noaccess, b.mutable
+	if err := b.protect(true, true); err != nil {
+		return err
+	}
+	Wipe(b.data)
+	b.dirty = true
+	return b.protect(read, write)
+}
+
 /*
 Destroy performs some security checks, securely wipes the contents of, and then releases a Buffer's memory back to the OS. If a security check fails, the process will attempt to wipe all it can before safely panicking.
 
@@ -196,54 +690,458 @@ func (b *Buffer) Destroy() {
 }
 
 func (b *Buffer) destroy() error {
+	hooks, err := b.release()
+
+	// Run the hooks once the lock has been released, most recently registered first.
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+	return err
+}
+
+/*
+Acquire registers a reader of a Buffer, preventing DestroyWhenReleased from destroying it until the returned function is called. This allows a Buffer that is shared between goroutines, such as an entry in a cache of secrets, to be retired while readers are still using it without the memory being freed underneath them.
+
+It returns false if the Buffer has already been destroyed or retired, in which case it must not be used. The returned function must be called exactly once when the reader is done; further calls do nothing. Destroy, Purge and Exit ignore acquisitions and destroy the Buffer immediately.
+*/
+func (b *Buffer) Acquire() (release func(), ok bool) {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.alive || b.retired {
+		return func() {}, false
+	}
+	b.readers++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.Lock()
+			b.readers--
+			last := b.readers == 0 && b.retired
+			b.Unlock()
+
+			// The last reader destroys a retired Buffer.
+			if last {
+				b.Destroy()
+			}
+		})
+	}, true
+}
+
+/*
+DestroyWhenReleased destroys a Buffer once every acquisition obtained from Acquire has been released. If there are none, it is destroyed straight away, as by Destroy. Otherwise no further acquisitions are allowed and the Buffer stays usable by the existing readers until the last one releases it.
+*/
+func (b *Buffer) DestroyWhenReleased() {
+	b.Lock()
+	b.retired = true
+	pending := b.alive && b.readers > 0
+	b.Unlock()
+
+	if !pending {
+		b.Destroy()
+	}
+}
+
+// Wipes and frees the memory of a Buffer, returning the hooks that should be run as a result.
+func (b *Buffer) release() ([]func(), error) {
 	// Attain a mutex lock on this Buffer.
 	b.Lock()
 	defer b.Unlock()
 
 	// Return if it's already destroyed.
 	if !b.alive {
-		return nil
+		return nil, nil
 	}
+	start := time.Now()
 
 	// Make all of the memory readable and writable.
 	if err := memcall.Protect(b.memory, memcall.ReadWrite()); err != nil {
-		return err
+		return nil, err
 	}
 	b.mutable = true
+	b.noaccess = false
 
 	// Wipe data field.
 	Wipe(b.data)
 
 	// Verify the canary
-	if !Equal(b.preguard, b.postguard) || !Equal(b.preguard[:len(b.canary)], b.canary) {
-		return errors.New("<memguard::core::buffer> canary verification failed; buffer overflow detected")
+	if !b.canaryIntact() {
+		return nil, errors.New("<memguard::core::buffer> canary verification failed; buffer overflow detected")
 	}
 
-	// Wipe the memory.
-	Wipe(b.memory)
-
-	// Unlock pages locked into memory.
-	if err := memcall.Unlock(b.inner); err != nil {
-		return err
+	// Wipe the memory. The canary of shared memory is left in place for the other Buffers using it.
+	if b.shared {
+		Wipe(b.preguard)
+		Wipe(b.postguard)
+	} else {
+		Wipe(b.memory)
 	}
 
-	// Free all related memory.
-	if err := memcall.Free(b.memory); err != nil {
-		return err
+	if b.arena != nil {
+		// Return the memory to the arena it was allocated from.
+		if err := b.arena.release(b.memory); err != nil {
+			return nil, err
+		}
+	} else {
+		// Unlock pages locked into memory.
+		if !b.unlocked {
+			if err := unlockMemory(b.inner); err != nil {
+				return nil, err
+			}
+			addLockedBytes(-len(b.inner))
+		}
+
+		// Free all related memory.
+		if b.shared {
+			if err := b.unmapShared(); err != nil {
+				return nil, err
+			}
+		} else if err := freeMemory(b.memory); err != nil {
+			return nil, err
+		}
 	}
+	atomic.AddInt64(&destroyNanos, int64(time.Since(start)))
+	atomic.AddInt64(&destroyedBuffers, 1)
 
 	// Reset the fields.
 	b.alive = false
 	b.mutable = false
+	b.permanent = false
 	b.data = nil
 	b.memory = nil
 	b.preguard = nil
 	b.inner = nil
 	b.postguard = nil
 	b.canary = nil
+	b.arena = nil
+	b.shared = false
+	b.unlocked = false
+	b.autoProtect = false
+
+	hooks := b.onDestroy
+	b.onDestroy = nil
+	return hooks, nil
+}
+
+/*
+PeakLockedBytes returns the highest total number of bytes that have been locked into memory by Buffers and Arenas at any one time over the lifetime of the process. This includes the space used by canaries but not the guard pages of Buffers allocated individually, which are never locked. It can be used to choose an appropriate value for the memory locking limit (RLIMIT_MEMLOCK on Unix systems).
+*/
+func PeakLockedBytes() int {
+	return int(atomic.LoadInt64(&peakLockedBytes))
+}
+
+// Stats holds counters describing the Buffers that have been destroyed over the lifetime of the process.
+type Stats struct {
+	Destroyed   int64         // Number of Buffers destroyed
+	DestroyTime time.Duration // Total time spent wiping and freeing their memory
+}
+
+/*
+GetStats returns a snapshot of the counters kept about destroyed Buffers, whether they were destroyed individually, by Purge or by Exit. Dividing DestroyTime by Destroyed gives the average cost of tearing down a Buffer, which can be used to diagnose slow shutdowns when there are many large Buffers. Buffers whose destruction fails are not counted.
+*/
+func GetStats() Stats {
+	return Stats{
+		Destroyed:   atomic.LoadInt64(&destroyedBuffers),
+		DestroyTime: time.Duration(atomic.LoadInt64(&destroyNanos)),
+	}
+}
+
+// Adjusts the total number of locked bytes by n, raising the peak if it has been exceeded.
+func addLockedBytes(n int) {
+	total := atomic.AddInt64(&lockedBytes, int64(n))
+	for {
+		peak := atomic.LoadInt64(&peakLockedBytes)
+		if total <= peak || atomic.CompareAndSwapInt64(&peakLockedBytes, peak, total) {
+			return
+		}
+	}
+}
+
+/*
+Consume removes the first n bytes of data from a Buffer and returns them inside a new Buffer. The remaining data is left in place and the consumed region is wiped and absorbed into the canary, so the Buffer shrinks without being reallocated.
+
+The Buffer must be alive and mutable, and n must be between one and the size of the data inclusive. Consuming all of the data leaves an empty but live Buffer.
+*/
+func (b *Buffer) Consume(n int) (c *Buffer, err error) {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check the state of the buffer and the bounds.
+	if !b.alive {
+		return nil, ErrBufferExpired
+	}
+	if !b.mutable {
+		return nil, ErrBufferImmutable
+	}
+	if n < 1 {
+		return nil, ErrNullBuffer
+	}
+	if n > len(b.data) {
+		return nil, ErrOutOfBounds
+	}
+	if b.shared {
+		return nil, ErrSharedBuffer
+	}
+
+	// Make sure the data is accessible.
+	restore, err := b.relax(true)
+	if err != nil {
+		return nil, err
+	}
+	// The consumed Buffer is only handed out if the protection of the remaining data is restored.
+	defer func() {
+		if rerr := restore(); rerr != nil && err == nil {
+			c.Destroy()
+			c, err = nil, rerr
+		}
+	}()
+
+	// Copy the prefix into its own Buffer.
+	c, err = NewBuffer(n)
+	if err != nil {
+		return nil, err
+	}
+	Copy(c.data, b.data[:n])
+
+	// Wipe the prefix and absorb it into a fresh canary. The guard pages only hold as much of the old canary as it covered, so it cannot be extended from them.
+	Wipe(b.data[:n])
+	if err := b.renewCanary(len(b.canary) + n); err != nil {
+		c.Destroy()
+		return nil, err
+	}
+
+	// Shrink the data region.
+	b.dirty = true
+	b.data = b.data[n:]
+
+	return c, nil
+}
+
+/*
+Grow extends the data of a Buffer by n bytes, which are appended after the existing data and set to zero. The data is moved into a newly allocated region of guarded memory and the old region is destroyed, so any slice previously returned by Data is no longer valid. The protection of the memory is preserved.
+
+The Buffer must be alive and mutable, and n must not be negative.
+*/
+func (b *Buffer) Grow(n int) (err error) {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check the state of the buffer and the size.
+	if !b.alive {
+		return ErrBufferExpired
+	}
+	if !b.mutable {
+		return ErrBufferImmutable
+	}
+	if n < 0 {
+		return ErrOutOfBounds
+	}
+	if n == 0 {
+		return nil
+	}
+	if b.shared {
+		return ErrSharedBuffer
+	}
+
+	// Allocate the new region inside a temporary Buffer.
+	c, err := NewBufferWithOptions(len(b.data)+n, BufferOptions{NoLock: b.unlocked})
+	if err != nil {
+		return err
+	}
+
+	// Copy the data across. The protection is restored on whichever region the Buffer holds on return.
+	restore, err := b.relax(false)
+	if err != nil {
+		c.destroy()
+		buffers.remove(c)
+		return err
+	}
+	defer func() {
+		if rerr := restore(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+	Copy(c.data, b.data)
+
+	// Exchange the regions so that the temporary Buffer holds the old one.
+	b.dirty = true
+	b.data, c.data = c.data, b.data
+	b.memory, c.memory = c.memory, b.memory
+	b.preguard, c.preguard = c.preguard, b.preguard
+	b.inner, c.inner = c.inner, b.inner
+	b.postguard, c.postguard = c.postguard, b.postguard
+	b.canary, c.canary = c.canary, b.canary
+	b.canarySum, c.canarySum = c.canarySum, b.canarySum
+	b.arena, c.arena = c.arena, b.arena
+	b.unlocked, c.unlocked = c.unlocked, b.unlocked
+
+	// Destroy the old region.
+	err = c.destroy()
+	buffers.remove(c)
+	return err
+}
+
+/*
+Resize wipes the data of a Buffer and changes its length in place, without allocating, so that the Buffer can be reused for a value of a different size. The new length may be anything up to the capacity of the Buffer, and the space in front of the data is filled with the canary as usual.
+
+An error is returned if the Buffer has been destroyed, is immutable, or shares its memory with other processes. ErrNullBuffer is returned if the size is less than one, and ErrOutOfBounds if it exceeds the capacity.
+*/
+func (b *Buffer) Resize(size int) (err error) {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	// Check the state of the buffer and the bounds.
+	if !b.alive {
+		return ErrBufferExpired
+	}
+	if !b.mutable {
+		return ErrBufferImmutable
+	}
+	if size < 1 {
+		return ErrNullBuffer
+	}
+	if size > len(b.inner) {
+		return ErrOutOfBounds
+	}
+	if b.shared {
+		return ErrSharedBuffer
+	}
+
+	// Make sure the data is accessible.
+	restore, err := b.relax(true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := restore(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	// Move the boundary between the canary and the data, filling in a fresh canary. The old canary may be empty, so it cannot be extended.
+	Wipe(b.data)
+	if err := b.renewCanary(len(b.inner) - size); err != nil {
+		return err
+	}
+	b.data = getBytes(&b.inner[len(b.inner)-size], size)
+	Wipe(b.data)
+	b.dirty = true
+
 	return nil
 }
 
+// Capacity returns the largest size that a Buffer can be given by Resize, which is its size rounded up to a whole number of pages. It returns zero once the buffer has been destroyed.
+func (b *Buffer) Capacity() int {
+	b.RLock()
+	defer b.RUnlock()
+	return len(b.inner)
+}
+
+/*
+Duplicate returns an independent copy of a Buffer in newly allocated memory. The copy has the same protection as the original, keeps its memory inaccessible between accesses if the original does, is permanently frozen if the original was frozen with FreezeImmutable, and is left unlocked if the original was allocated with BufferOptions.NoLock. It is always allocated individually, even if the original came from an Arena or shares its memory with other processes.
+
+An error is returned if the Buffer has been destroyed, and ErrNullBuffer is returned if it holds no data.
+*/
+func (b *Buffer) Duplicate() (*Buffer, error) {
+	// Attain lock.
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.alive {
+		return nil, ErrBufferExpired
+	}
+
+	// Allocate the copy and move the data across.
+	c, err := NewBufferWithOptions(len(b.data), BufferOptions{NoLock: b.unlocked})
+	if err != nil {
+		return nil, err
+	}
+	restore, err := b.relax(false)
+	if err == nil {
+		Copy(c.data, b.data)
+		err = restore()
+	}
+
+	// Replicate the protection and flags.
+	if err == nil {
+		err = c.protect(!b.noaccess, b.mutable)
+	}
+	if err != nil {
+		c.destroy()
+		buffers.remove(c)
+		return nil, err
+	}
+	c.permanent = b.permanent
+	c.autoProtect = b.autoProtect
+
+	return c, nil
+}
+
+// Generates a new random value for the guard pages and fills in a canary of a given length from it. The caller must hold the write lock and ensure the inner region is writable. The canary is left unchanged if no random bytes are available.
+func (b *Buffer) renewCanary(length int) error {
+	if err := memcall.Protect(b.preguard, memcall.ReadWrite()); err != nil {
+		return err
+	}
+	if err := memcall.Protect(b.postguard, memcall.ReadWrite()); err != nil {
+		return err
+	}
+
+	err := Scramble(b.preguard)
+	if err == nil {
+		Copy(b.postguard, b.preguard)
+		b.canary = getBytes(&b.inner[0], length)
+		for i := range b.canary {
+			b.canary[i] = b.preguard[i%pageSize]
+		}
+		b.canarySum = blake2b.Sum256(b.canary)
+	}
+
+	// Make the guard pages inaccessible again.
+	if perr := memcall.Protect(b.preguard, memcall.NoAccess()); perr != nil {
+		return perr
+	}
+	if perr := memcall.Protect(b.postguard, memcall.NoAccess()); perr != nil {
+		return perr
+	}
+	return err
+}
+
+// Reports whether the canary and guard page values are intact. The caller must ensure the memory is readable.
+func (b *Buffer) canaryIntact() bool {
+	ok := Equal(b.preguard, b.postguard)
+
+	// The canary repeats the guard page value if it is longer than a page.
+	for i := 0; i < len(b.canary); i += pageSize {
+		end := i + pageSize
+		if end > len(b.canary) {
+			end = len(b.canary)
+		}
+		ok = Equal(b.preguard[:end-i], b.canary[i:end]) && ok
+	}
+	return ok
+}
+
+/*
+OnDestroy registers a function to be called once a Buffer has been destroyed, after its memory has been wiped and freed, so that associated resources such as file descriptors or timers can be cleaned up. Functions are called in the reverse order to which they were registered, whether the Buffer is destroyed directly, by a purge, or when the process exits, but not if destroying it fails.
+
+Destroying the Buffer again from within a function does nothing. Since Purge calls the functions while holding the session key, they must not call Purge or use Enclaves. If the Buffer has already been destroyed, the function is called immediately.
+*/
+func (b *Buffer) OnDestroy(fn func()) {
+	b.Lock()
+	if b.alive {
+		b.onDestroy = append(b.onDestroy, fn)
+		b.Unlock()
+		return
+	}
+	b.Unlock()
+	fn()
+}
+
 // Alive returns true if the buffer has not been destroyed.
 func (b *Buffer) Alive() bool {
 	b.RLock()
@@ -258,6 +1156,34 @@ func (b *Buffer) Mutable() bool {
 	return b.mutable
 }
 
+// Readable returns true if the data of the buffer can currently be read directly, without going through Access. It returns false if the buffer has been destroyed or its memory is inaccessible.
+func (b *Buffer) Readable() bool {
+	b.RLock()
+	defer b.RUnlock()
+	return b.alive && !b.noaccess
+}
+
+// Writable returns true if the data of the buffer can currently be written directly, without going through Access. It returns false if the buffer has been destroyed, is immutable, or its memory is inaccessible.
+func (b *Buffer) Writable() bool {
+	b.RLock()
+	defer b.RUnlock()
+	return b.alive && !b.noaccess && b.mutable
+}
+
+// Dirty returns true if the data may have been modified, through Access with write set or by Consume or Grow, since the buffer was created or ClearDirty was last called. It returns false once the buffer has been destroyed.
+func (b *Buffer) Dirty() bool {
+	b.RLock()
+	defer b.RUnlock()
+	return b.alive && b.dirty
+}
+
+// ClearDirty resets the flag reported by Dirty.
+func (b *Buffer) ClearDirty() {
+	b.Lock()
+	defer b.Unlock()
+	b.dirty = false
+}
+
 // BufferList stores a list of buffers in a thread-safe manner.
 type bufferList struct {
 	sync.RWMutex
@@ -2,8 +2,15 @@ package core
 
 import (
 	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 	"unsafe"
+
+	"github.com/awnumar/memcall"
+	"golang.org/x/crypto/blake2b"
 )
 
 func TestNewBuffer(t *testing.T) {
@@ -91,6 +98,146 @@ func TestLotsOfAllocs(t *testing.T) {
 	}
 }
 
+func TestNewBufferWithTimeout(t *testing.T) {
+	b, err := NewBufferWithTimeout(32, time.Second)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.Alive() || len(b.Data()) != 32 {
+		t.Error("invalid buffer")
+	}
+	b.Destroy()
+
+	// A timeout that has already expired fails without allocating anything.
+	func() {
+		defer func(alloc func(int) ([]byte, error)) { allocMemory = alloc }(allocMemory)
+		allocMemory = func(int) ([]byte, error) {
+			t.Error("memory allocated for expired timeout")
+			return nil, ErrLockTimeout
+		}
+		for _, timeout := range []time.Duration{0, -time.Second} {
+			if b, err := NewBufferWithTimeout(32, timeout); err != ErrLockTimeout || b != nil {
+				t.Error("expected ErrLockTimeout; got", err)
+			}
+		}
+	}()
+
+	// Mock the system calls so that locking blocks until released.
+	defer func(lock, unlock, free func([]byte) error) {
+		lockMemory, unlockMemory, freeMemory = lock, unlock, free
+	}(lockMemory, unlockMemory, freeMemory)
+	release := make(chan struct{})
+	unlocked := make(chan []byte, 1)
+	freed := make(chan []byte, 1)
+	lockMemory = func([]byte) error {
+		<-release
+		return nil
+	}
+	unlockMemory = func(b []byte) error {
+		unlocked <- b
+		return nil
+	}
+	freeMemory = func(b []byte) error {
+		freed <- b
+		return memcall.Free(b)
+	}
+
+	live := atomic.LoadInt64(&lockedBytes)
+	b, err = NewBufferWithTimeout(32, 10*time.Millisecond)
+	if err != ErrLockTimeout {
+		t.Error("expected ErrLockTimeout; got", err)
+	}
+	if b != nil {
+		t.Error("expected nil buffer; got", b)
+	}
+	if atomic.LoadInt64(&lockedBytes) != live {
+		t.Error("locked total changed")
+	}
+
+	// The memory is cleaned up once the lock completes.
+	select {
+	case <-freed:
+		t.Error("memory freed while lock was in progress")
+	default:
+	}
+	close(release)
+	select {
+	case <-unlocked:
+	case <-time.After(time.Second):
+		t.Error("memory not unlocked")
+	}
+	select {
+	case <-freed:
+	case <-time.After(time.Second):
+		t.Error("memory not freed")
+	}
+
+	// Failures to lock are returned.
+	lockMemory = func([]byte) error {
+		return errors.New("lock failure")
+	}
+	if _, err := NewBufferWithTimeout(32, time.Second); err == nil || err == ErrLockTimeout {
+		t.Error("expected lock failure; got", err)
+	}
+	select {
+	case <-freed:
+	default:
+		t.Error("memory not freed")
+	}
+}
+
+func TestNewBufferWithOptions(t *testing.T) {
+	// Count the calls to lock and unlock memory.
+	defer func(lock, unlock func([]byte) error) {
+		lockMemory, unlockMemory = lock, unlock
+	}(lockMemory, unlockMemory)
+	var locks, unlocks int
+	lockMemory = func(b []byte) error {
+		locks++
+		return memcall.Lock(b)
+	}
+	unlockMemory = func(b []byte) error {
+		unlocks++
+		return memcall.Unlock(b)
+	}
+
+	// Memory is not locked with NoLock.
+	b, err := NewBufferWithOptions(32, BufferOptions{NoLock: true, Timeout: time.Second})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.Alive() || len(b.Data()) != 32 || !b.unlocked {
+		t.Error("invalid buffer")
+	}
+	if err := b.Grow(pageSize); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.unlocked || len(b.Data()) != 32+pageSize {
+		t.Error("invalid buffer after growing")
+	}
+	b.Destroy()
+	if locks != 0 || unlocks != 0 {
+		t.Error("memory was locked;", locks, unlocks)
+	}
+
+	// Options combine.
+	b, err = NewBufferWithOptions(32, BufferOptions{Timeout: time.Second, Bind: true, Node: -1})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.Alive() || b.unlocked {
+		t.Error("invalid buffer")
+	}
+	b.Destroy()
+	if locks != 1 || unlocks != 1 {
+		t.Error("memory was not locked and unlocked;", locks, unlocks)
+	}
+
+	if _, err := NewBufferWithOptions(0, BufferOptions{NoLock: true}); err != ErrNullBuffer {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+}
+
 func TestData(t *testing.T) {
 	b, err := NewBuffer(32)
 	if err != nil {
@@ -170,6 +317,899 @@ func TestBufferState(t *testing.T) {
 	}
 }
 
+func TestFreezeImmutable(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+
+	if err := b.FreezeImmutable(); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if b.Mutable() != false {
+		t.Error("state mismatch: mutability")
+	}
+
+	// Attempts to make the buffer mutable should be refused.
+	b.Melt()
+	if b.Mutable() {
+		t.Error("melt made an immutable buffer mutable")
+	}
+	if err := b.TryMelt(); err != ErrImmutable {
+		t.Error("expected ErrImmutable; got", err)
+	}
+	if err := b.Protect(true, true); err != ErrImmutable {
+		t.Error("expected ErrImmutable; got", err)
+	}
+	if err := b.Protect(false, true); err != ErrImmutable {
+		t.Error("expected ErrImmutable; got", err)
+	}
+	if b.Mutable() != false {
+		t.Error("state mismatch: mutability")
+	}
+
+	// Making it inaccessible is permitted and preserved.
+	if err := b.Protect(false, false); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if err := b.FreezeImmutable(); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.noaccess {
+		t.Error("state mismatch: access")
+	}
+
+	// It can still be destroyed.
+	b.Destroy()
+	if b.Alive() || b.permanent {
+		t.Error("buffer not destroyed")
+	}
+	if err := b.FreezeImmutable(); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	Scramble(b.Data())
+	value := make([]byte, 32)
+	copy(value, b.Data())
+
+	states := []struct {
+		read, write bool
+	}{
+		{false, false},
+		{false, true},
+		{true, false},
+		{true, true},
+	}
+	for _, s := range states {
+		if err := b.Protect(s.read, s.write); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if b.Mutable() != s.write {
+			t.Error("state mismatch: mutability", s)
+		}
+		if b.noaccess == s.read {
+			t.Error("state mismatch: access", s)
+		}
+
+		// Reads work regardless of the protection.
+		if err := b.Access(false, func(data []byte) error {
+			if !bytes.Equal(data, value) {
+				t.Error("data changed", s)
+			}
+			return nil
+		}); err != nil {
+			t.Error("unexpected error:", err)
+		}
+
+		// Writes only work if the buffer is mutable.
+		err := b.Access(true, func(data []byte) error {
+			data[0] ^= 0xff
+			data[0] ^= 0xff
+			return nil
+		})
+		if s.write && err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if !s.write && err != ErrBufferImmutable {
+			t.Error("expected ErrBufferImmutable; got", err)
+		}
+
+		// The protection should be restored afterwards.
+		if b.Mutable() != s.write || b.noaccess == s.read {
+			t.Error("protection not restored", s)
+		}
+	}
+
+	b.Protect(false, false)
+	b.Destroy()
+	if err := b.Protect(true, true); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if err := b.Access(false, func([]byte) error { return nil }); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestSetAutoProtect(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	Scramble(b.Data())
+	value := make([]byte, 32)
+	copy(value, b.Data())
+
+	if err := b.SetAutoProtect(true); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.noaccess || !b.Mutable() {
+		t.Error("expected inaccessible mutable memory")
+	}
+
+	// Changing the protection leaves the memory inaccessible.
+	states := []struct {
+		read, write bool
+	}{
+		{true, true},
+		{true, false},
+		{false, true},
+		{false, false},
+	}
+	for _, s := range states {
+		if err := b.Protect(s.read, s.write); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if !b.noaccess || b.Mutable() != s.write {
+			t.Error("state mismatch", s)
+		}
+	}
+	b.Melt()
+	if !b.noaccess || !b.Mutable() {
+		t.Error("state mismatch after melt")
+	}
+	b.Freeze()
+	if !b.noaccess || b.Mutable() {
+		t.Error("state mismatch after freeze")
+	}
+
+	// Access still works and restores the protection.
+	if err := b.Access(false, func(data []byte) error {
+		if !bytes.Equal(data, value) {
+			t.Error("data changed")
+		}
+		return nil
+	}); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if err := b.Access(true, func([]byte) error { return nil }); err != ErrBufferImmutable {
+		t.Error("expected ErrBufferImmutable; got", err)
+	}
+	b.Melt()
+	if err := b.Access(true, func(data []byte) error {
+		data[0] ^= 0xff
+		return nil
+	}); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	value[0] ^= 0xff
+	if !b.noaccess {
+		t.Error("protection not restored")
+	}
+
+	// Copies keep the setting.
+	c, err := b.Duplicate()
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !c.autoProtect || !c.noaccess {
+		t.Error("setting not replicated")
+	}
+	c.Destroy()
+
+	// Disabling makes the memory readable again.
+	if err := b.SetAutoProtect(false); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if b.noaccess || !bytes.Equal(b.Data(), value) {
+		t.Error("expected readable memory")
+	}
+	b.Freeze()
+	if b.noaccess {
+		t.Error("expected readable memory after freeze")
+	}
+
+	b.Destroy()
+	if err := b.SetAutoProtect(true); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	var calls int64
+	defer func(protect func([]byte, memcall.MemoryProtectionFlag) error) {
+		protectMemory = protect
+	}(protectMemory)
+	protectMemory = func(b []byte, flag memcall.MemoryProtectionFlag) error {
+		atomic.AddInt64(&calls, 1)
+		return memcall.Protect(b, flag)
+	}
+
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	defer b.Destroy()
+	Scramble(b.Data())
+	value := make([]byte, 32)
+	copy(value, b.Data())
+	b.Freeze()
+
+	read := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 64; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					if err := b.Access(false, func(data []byte) error {
+						if !bytes.Equal(data, value) {
+							t.Error("data changed")
+						}
+						return nil
+					}); err != nil {
+						t.Error("unexpected error:", err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	// Readable memory is never reprotected, whether or not it is mutable.
+	for _, melt := range []bool{false, true} {
+		if melt {
+			b.Melt()
+		}
+		atomic.StoreInt64(&calls, 0)
+		read()
+		if n := atomic.LoadInt64(&calls); n != 0 {
+			t.Error("expected no protection changes; got", n, "mutable:", melt)
+		}
+	}
+
+	// Inaccessible memory is relaxed and restored by every reader.
+	b.Protect(false, false)
+	atomic.StoreInt64(&calls, 0)
+	read()
+	if n := atomic.LoadInt64(&calls); n != 2*64*100 {
+		t.Error("expected a protection change on either side of every read; got", n)
+	}
+}
+
+func TestPeakLockedBytes(t *testing.T) {
+	live := atomic.LoadInt64(&lockedBytes)
+
+	// Allocate a burst of buffers larger than any previous usage.
+	size := int(live) + PeakLockedBytes() + pageSize
+	var burst []*Buffer
+	for i := 0; i < 4; i++ {
+		b, err := NewBuffer(size / 4)
+		if err != nil {
+			t.Error(err)
+		}
+		burst = append(burst, b)
+	}
+	total := int(atomic.LoadInt64(&lockedBytes))
+	if total < int(live)+size {
+		t.Error("locked total does not include burst; got", total)
+	}
+	if PeakLockedBytes() != total {
+		t.Error("peak does not match burst; got", PeakLockedBytes(), "expected", total)
+	}
+
+	// Freeing the burst should reduce the live total but not the peak.
+	for _, b := range burst {
+		b.Destroy()
+	}
+	if atomic.LoadInt64(&lockedBytes) != live {
+		t.Error("locked total not restored; got", atomic.LoadInt64(&lockedBytes), "expected", live)
+	}
+	if PeakLockedBytes() != total {
+		t.Error("peak changed after freeing; got", PeakLockedBytes(), "expected", total)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	before := GetStats()
+
+	// Purged buffers are counted.
+	for i := 0; i < 3; i++ {
+		if _, err := NewBuffer(pageSize); err != nil {
+			t.Error(err)
+		}
+	}
+	live := len(buffers.copy())
+	Purge()
+
+	after := GetStats()
+	if after.Destroyed < before.Destroyed+int64(live) {
+		t.Error("purged buffers not counted; got", after.Destroyed, "expected at least", before.Destroyed+int64(live))
+	}
+	if after.DestroyTime <= before.DestroyTime {
+		t.Error("destroy time did not increase")
+	}
+}
+
+func TestConsume(t *testing.T) {
+	b, err := NewBuffer(2 * pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	Scramble(b.data)
+	data := make([]byte, len(b.data))
+	copy(data, b.data)
+
+	// Consume successive prefixes.
+	for i := 0; len(data) > 0; i++ {
+		n := i + 1
+		if n > len(data) {
+			n = len(data)
+		}
+		c, err := b.Consume(n)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(c.data, data[:n]) {
+			t.Error("consumed data does not match")
+		}
+		data = data[n:]
+		if !bytes.Equal(b.data, data) {
+			t.Error("remaining data does not match")
+		}
+		if len(b.canary)+len(b.data) != len(b.inner) {
+			t.Error("canary does not cover the freed region")
+		}
+		if blake2b.Sum256(b.canary) != b.canarySum {
+			t.Error("canary sum not updated")
+		}
+		if len(b.canary) >= 32 && bytes.Equal(b.canary, make([]byte, len(b.canary))) {
+			t.Error("extended canary is all zero")
+		}
+		c.Destroy()
+	}
+	if len(b.data) != 0 || !b.alive {
+		t.Error("expected empty live buffer")
+	}
+
+	// Errors.
+	if _, err := b.Consume(1); err != ErrOutOfBounds {
+		t.Error("expected ErrOutOfBounds; got", err)
+	}
+	if _, err := b.Consume(0); err != ErrNullBuffer {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+	b.Destroy() // verifies the extended canary
+	if _, err := b.Consume(1); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+
+	b, err = NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+	b.Freeze()
+	if _, err := b.Consume(1); err != ErrBufferImmutable {
+		t.Error("expected ErrBufferImmutable; got", err)
+	}
+	b.Destroy()
+
+	// If the protection cannot be restored, the consumed Buffer is destroyed rather than returned.
+	b, err = NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+	defer b.Destroy()
+	b.Protect(false, true)
+	defer func(protect func([]byte, memcall.MemoryProtectionFlag) error) {
+		protectMemory = protect
+	}(protectMemory)
+	protectMemory = func(b []byte, flag memcall.MemoryProtectionFlag) error {
+		if flag == memcall.NoAccess() {
+			return ErrLockTimeout
+		}
+		return memcall.Protect(b, flag)
+	}
+	if c, err := b.Consume(1); err != ErrLockTimeout || c != nil {
+		t.Error("expected restore error and no buffer; got", err)
+	}
+}
+
+func TestGrow(t *testing.T) {
+	b, err := NewBuffer(8)
+	if err != nil {
+		t.Error(err)
+	}
+	copy(b.Data(), "01234567")
+
+	// Grow within and beyond the current page.
+	for _, n := range []int{0, 8, pageSize} {
+		size := len(b.Data())
+		if err := b.Grow(n); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if len(b.Data()) != size+n {
+			t.Error("invalid size; got", len(b.Data()), "expected", size+n)
+		}
+		if !bytes.Equal(b.Data()[:8], []byte("01234567")) {
+			t.Error("data not preserved")
+		}
+		if !bytes.Equal(b.Data()[size:], make([]byte, n)) {
+			t.Error("new region not zeroed")
+		}
+		if !buffers.exists(b) {
+			t.Error("buffer not in list")
+		}
+	}
+
+	// Protection is preserved.
+	b.Protect(false, true)
+	if err := b.Grow(1); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.noaccess || !b.Mutable() {
+		t.Error("protection not preserved")
+	}
+	b.Protect(true, true)
+	if !bytes.Equal(b.Data()[:8], []byte("01234567")) {
+		t.Error("data not preserved")
+	}
+
+	// Invalid arguments and states.
+	if err := b.Grow(-1); err != ErrOutOfBounds {
+		t.Error("expected ErrOutOfBounds; got", err)
+	}
+	b.Freeze()
+	if err := b.Grow(1); err != ErrBufferImmutable {
+		t.Error("expected ErrBufferImmutable; got", err)
+	}
+	b.Destroy() // also verifies the canary
+	if err := b.Grow(1); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+
+	// Restoring the protection of the new region can still fail after the old region has been destroyed.
+	b, err = NewBuffer(8)
+	if err != nil {
+		t.Error(err)
+	}
+	defer b.Destroy()
+	b.Protect(false, true)
+	defer func(protect func([]byte, memcall.MemoryProtectionFlag) error) {
+		protectMemory = protect
+	}(protectMemory)
+	protectMemory = func(b []byte, flag memcall.MemoryProtectionFlag) error {
+		if flag == memcall.NoAccess() {
+			return ErrLockTimeout
+		}
+		return memcall.Protect(b, flag)
+	}
+	if err := b.Grow(8); err != ErrLockTimeout {
+		t.Error("expected restore error; got", err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	if b.Capacity() != pageSize {
+		t.Error("unexpected capacity", b.Capacity())
+	}
+
+	for _, size := range []int{pageSize, 1, 100, pageSize - 1, 32} {
+		Scramble(b.Data())
+		if err := b.Resize(size); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if len(b.Data()) != size || !bytes.Equal(b.Data(), make([]byte, size)) {
+			t.Error("data not wiped and resized to", size)
+		}
+		if len(b.canary) != pageSize-size || blake2b.Sum256(b.canary) != b.canarySum {
+			t.Error("canary not maintained for size", size)
+		}
+		if !b.Dirty() {
+			t.Error("resize did not mark buffer dirty")
+		}
+	}
+
+	// Multi-page buffers repeat the canary across pages.
+	c, err := NewBuffer(3 * pageSize)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	if err := c.Resize(10); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if err := c.destroy(); err != nil {
+		t.Error("canary not maintained across pages:", err)
+	}
+	buffers.remove(c)
+
+	// Buffers created with an empty canary get a random one.
+	d, err := NewBuffer(pageSize)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	if err := d.Resize(10); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if len(d.canary) != pageSize-10 || bytes.Equal(d.canary, make([]byte, len(d.canary))) {
+		t.Error("canary is empty or all zero after resize")
+	}
+	if err := d.destroy(); err != nil {
+		t.Error("canary not maintained:", err)
+	}
+	buffers.remove(d)
+
+	// Invalid sizes and states.
+	if err := b.Resize(0); err != ErrNullBuffer {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+	if err := b.Resize(pageSize + 1); err != ErrOutOfBounds {
+		t.Error("expected ErrOutOfBounds; got", err)
+	}
+	b.Freeze()
+	if err := b.Resize(16); err != ErrBufferImmutable {
+		t.Error("expected ErrBufferImmutable; got", err)
+	}
+	b.Melt()
+
+	// Inaccessible memory is relaxed and restored.
+	b.Protect(false, true)
+	if err := b.Resize(16); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.noaccess {
+		t.Error("protection not restored")
+	}
+
+	// The data is resized even if its protection cannot be restored afterwards, and the error is returned.
+	func() {
+		defer func(protect func([]byte, memcall.MemoryProtectionFlag) error) {
+			protectMemory = protect
+		}(protectMemory)
+		protectMemory = func(b []byte, flag memcall.MemoryProtectionFlag) error {
+			if flag == memcall.NoAccess() {
+				return ErrLockTimeout
+			}
+			return memcall.Protect(b, flag)
+		}
+		if err := b.Resize(8); err != ErrLockTimeout {
+			t.Error("expected restore error; got", err)
+		}
+	}()
+
+	// The canary is verified on destruction.
+	if err := b.destroy(); err != nil {
+		t.Error("canary not maintained:", err)
+	}
+	buffers.remove(b)
+	if err := b.Resize(16); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if b.Capacity() != 0 {
+		t.Error("expected zero capacity for destroyed buffer")
+	}
+}
+
+func TestVerifyCanaryOnAccess(t *testing.T) {
+	defer SetVerifyCanaryOnAccess(false)
+
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+	c, err := NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+	defer c.Destroy()
+
+	// Intact buffers are unaffected, including after their layout changes.
+	SetVerifyCanaryOnAccess(true)
+	if _, err := c.Consume(8); err != nil {
+		t.Error(err)
+	}
+	if err := c.Grow(pageSize); err != nil {
+		t.Error(err)
+	}
+	c.Protect(false, true)
+	if err := c.Access(true, func([]byte) error { return nil }); err != nil {
+		t.Error(err)
+	}
+
+	// Simulate writing past the start of the data.
+	b.canary[len(b.canary)-1] ^= 0xff
+
+	// Corruption is not noticed when verification is disabled.
+	SetVerifyCanaryOnAccess(false)
+	if err := b.Access(false, func([]byte) error { return nil }); err != nil {
+		t.Error(err)
+	}
+
+	// It is caught on the next access when enabled.
+	SetVerifyCanaryOnAccess(true)
+	called := false
+	if !panics(func() {
+		b.Access(false, func([]byte) error {
+			called = true
+			return nil
+		})
+	}) {
+		t.Error("expected corruption to be detected")
+	}
+	if called {
+		t.Error("function called despite corruption")
+	}
+	if !bytes.Equal(b.Data(), make([]byte, 32)) || c.Alive() {
+		t.Error("expected buffers to be purged")
+	}
+
+	// Repair the canary so that the buffer can be destroyed.
+	b.canary[len(b.canary)-1] ^= 0xff
+	SetVerifyCanaryOnAccess(false)
+	b.Destroy()
+}
+
+func TestVerifyLockOnCreate(t *testing.T) {
+	defer SetVerifyLockOnCreate(false)
+	defer func(res func([]byte) (bool, error), unlock, free func([]byte) error) {
+		residentMemory, unlockMemory, freeMemory = res, unlock, free
+	}(residentMemory, unlockMemory, freeMemory)
+
+	// Simulate locking that has no effect.
+	var checks, unlocks, frees int
+	residentMemory = func([]byte) (bool, error) {
+		checks++
+		return false, nil
+	}
+	unlockMemory = func(b []byte) error {
+		unlocks++
+		return memcall.Unlock(b)
+	}
+	freeMemory = func(b []byte) error {
+		frees++
+		return memcall.Free(b)
+	}
+
+	// Nothing is checked while verification is disabled.
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	b.Destroy()
+	if checks != 0 {
+		t.Error("residency checked while disabled")
+	}
+
+	// Ineffective locking is reported and the memory is released.
+	SetVerifyLockOnCreate(true)
+	checks, unlocks, frees = 0, 0, 0
+	peak := PeakLockedBytes()
+	if b, err := NewBuffer(32); err != ErrLockIneffective || b != nil {
+		t.Error("expected ErrLockIneffective; got", err)
+	}
+	if checks != 1 || unlocks != 1 || frees != 1 {
+		t.Error("memory not released;", checks, unlocks, frees)
+	}
+	if PeakLockedBytes() != peak {
+		t.Error("ineffectively locked memory was counted")
+	}
+
+	// Errors from the check are returned.
+	residentMemory = func([]byte) (bool, error) {
+		return false, errors.New("check failed")
+	}
+	if _, err := NewBuffer(32); err == nil || err.Error() != "check failed" {
+		t.Error("expected check error; got", err)
+	}
+
+	// Memory that is not locked is not checked.
+	checks = 0
+	residentMemory = func([]byte) (bool, error) {
+		checks++
+		return false, nil
+	}
+	b, err = NewBufferWithOptions(32, BufferOptions{NoLock: true})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	b.Destroy()
+	if checks != 0 {
+		t.Error("unlocked memory was checked")
+	}
+}
+
+func TestReadableWritable(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, c := range []struct {
+		read, write        bool
+		readable, writable bool
+	}{
+		{true, true, true, true},
+		{true, false, true, false},
+		{false, true, false, false},
+		{false, false, false, false},
+	} {
+		if err := b.Protect(c.read, c.write); err != nil {
+			t.Error(err)
+		}
+		if b.Readable() != c.readable || b.Writable() != c.writable {
+			t.Error("unexpected predicates for", c, b.Readable(), b.Writable())
+		}
+	}
+
+	b.Destroy()
+	if b.Readable() || b.Writable() {
+		t.Error("destroyed buffer reported accessible")
+	}
+}
+
+func TestDirty(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	if b.Dirty() {
+		t.Error("new buffer should be clean")
+	}
+
+	// Reads leave the flag alone.
+	b.Access(false, func([]byte) error { return nil })
+	if b.Dirty() {
+		t.Error("read marked buffer dirty")
+	}
+
+	// Writes set it, even if they fail.
+	b.Access(true, func([]byte) error { return errors.New("failed") })
+	if !b.Dirty() {
+		t.Error("write did not mark buffer dirty")
+	}
+	b.ClearDirty()
+	if b.Dirty() {
+		t.Error("flag not cleared")
+	}
+
+	// Refused writes do not.
+	b.Freeze()
+	b.Access(true, func([]byte) error { return nil })
+	if b.Dirty() {
+		t.Error("refused write marked buffer dirty")
+	}
+	b.Melt()
+
+	// Resizing sets it.
+	if err := b.Grow(8); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.Dirty() {
+		t.Error("grow did not mark buffer dirty")
+	}
+	b.ClearDirty()
+	c, err := b.Consume(8)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	c.Destroy()
+	if !b.Dirty() {
+		t.Error("consume did not mark buffer dirty")
+	}
+
+	b.Destroy()
+	if b.Dirty() {
+		t.Error("destroyed buffer should not be dirty")
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	states := []struct {
+		noLock bool
+		setup  func(b *Buffer)
+	}{
+		{false, func(b *Buffer) {}},
+		{false, func(b *Buffer) { b.Freeze() }},
+		{false, func(b *Buffer) { b.FreezeImmutable() }},
+		{false, func(b *Buffer) { b.Protect(false, true) }},
+		{false, func(b *Buffer) { b.Protect(false, false) }},
+		{true, func(b *Buffer) {}},
+		{true, func(b *Buffer) { b.FreezeImmutable() }},
+		{true, func(b *Buffer) { b.Protect(false, false); b.FreezeImmutable() }},
+	}
+
+	for i, s := range states {
+		b, err := NewBufferWithOptions(32, BufferOptions{NoLock: s.noLock})
+		if err != nil {
+			t.Error(err)
+		}
+		Scramble(b.Data())
+		s.setup(b)
+
+		c, err := b.Duplicate()
+		if err != nil {
+			t.Error(i, "unexpected error:", err)
+		}
+		if c.mutable != b.mutable || c.noaccess != b.noaccess || c.permanent != b.permanent || c.unlocked != b.unlocked {
+			t.Error(i, "flags do not match", c.mutable, c.noaccess, c.permanent, c.unlocked)
+		}
+		if &c.data[0] == &b.data[0] {
+			t.Error(i, "copy shares memory")
+		}
+		b.Access(false, func(x []byte) error {
+			return c.Access(false, func(y []byte) error {
+				if !bytes.Equal(x, y) {
+					t.Error(i, "data does not match")
+				}
+				return nil
+			})
+		})
+
+		b.Destroy()
+		c.Destroy()
+	}
+
+	b, _ := NewBuffer(32)
+	b.Destroy()
+	if _, err := b.Duplicate(); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestClear(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+	Scramble(b.Data())
+	b.Freeze()
+	b.ClearDirty()
+
+	if err := b.Clear(); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(b.Data(), make([]byte, 32)) {
+		t.Error("buffer was not cleared")
+	}
+	if b.mutable || b.noaccess || !b.Dirty() {
+		t.Error("unexpected state after clear")
+	}
+
+	b.Melt()
+	Scramble(b.Data())
+	b.FreezeImmutable()
+	if err := b.Clear(); err != ErrImmutable {
+		t.Error("expected ErrImmutable; got", err)
+	}
+	if bytes.Equal(b.Data(), make([]byte, 32)) {
+		t.Error("immutable buffer was cleared")
+	}
+
+	b.Destroy()
+	if err := b.Clear(); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
 func TestDestroy(t *testing.T) {
 	// Allocate a new buffer.
 	b, err := NewBuffer(32)
@@ -229,6 +1269,120 @@ func TestDestroy(t *testing.T) {
 	}
 }
 
+func TestOnDestroy(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+
+	var calls []int
+	for i := 0; i < 3; i++ {
+		i := i
+		b.OnDestroy(func() {
+			if b.Alive() {
+				t.Error("hook called before destruction")
+			}
+			b.Destroy() // must not re-enter
+			calls = append(calls, i)
+		})
+	}
+	if len(calls) != 0 {
+		t.Error("hooks called early")
+	}
+
+	b.Destroy()
+	if len(calls) != 3 || calls[0] != 2 || calls[1] != 1 || calls[2] != 0 {
+		t.Error("hooks not called in reverse order; got", calls)
+	}
+
+	// Hooks are only called once.
+	b.Destroy()
+	if len(calls) != 3 {
+		t.Error("hooks called again; got", calls)
+	}
+
+	// Hooks registered on a destroyed buffer are called immediately.
+	b.OnDestroy(func() { calls = append(calls, 3) })
+	if len(calls) != 4 || calls[3] != 3 {
+		t.Error("hook not called immediately; got", calls)
+	}
+
+	// Purging calls them too.
+	c, err := NewBuffer(32)
+	if err != nil {
+		t.Error("expected nil err; got", err)
+	}
+	called := false
+	c.OnDestroy(func() { called = true })
+	Purge()
+	if !called {
+		t.Error("hook not called on purge")
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	b, err := NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+
+	r1, ok := b.Acquire()
+	if !ok {
+		t.Error("failed to acquire live buffer")
+	}
+	r2, ok := b.Acquire()
+	if !ok {
+		t.Error("failed to acquire live buffer")
+	}
+
+	// Retiring defers destruction and refuses new readers.
+	b.DestroyWhenReleased()
+	if !b.Alive() {
+		t.Error("buffer destroyed while acquired")
+	}
+	if _, ok := b.Acquire(); ok {
+		t.Error("acquired retired buffer")
+	}
+
+	// Releasing twice counts once.
+	r1()
+	r1()
+	if !b.Alive() {
+		t.Error("buffer destroyed while acquired")
+	}
+	r2()
+	if b.Alive() {
+		t.Error("buffer not destroyed after last release")
+	}
+	if _, ok := b.Acquire(); ok {
+		t.Error("acquired destroyed buffer")
+	}
+
+	// Without readers it is destroyed straight away.
+	b, err = NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+	release, _ := b.Acquire()
+	release()
+	b.DestroyWhenReleased()
+	if b.Alive() {
+		t.Error("buffer not destroyed")
+	}
+
+	// Destroy ignores acquisitions.
+	b, err = NewBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+	release, _ = b.Acquire()
+	b.Destroy()
+	if b.Alive() {
+		t.Error("buffer not destroyed")
+	}
+	release()
+}
+
 func TestBufferList(t *testing.T) {
 	// Create a new BufferList for testing with.
 	l := new(bufferList)
@@ -290,3 +1444,32 @@ func TestBufferList(t *testing.T) {
 	}
 	l.remove(a)
 }
+
+func BenchmarkConcurrentReaders(b *testing.B) {
+	var calls int64
+	defer func(protect func([]byte, memcall.MemoryProtectionFlag) error) {
+		protectMemory = protect
+	}(protectMemory)
+	protectMemory = func(b []byte, flag memcall.MemoryProtectionFlag) error {
+		atomic.AddInt64(&calls, 1)
+		return memcall.Protect(b, flag)
+	}
+
+	buf, err := NewBuffer(32)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer buf.Destroy()
+	buf.Freeze()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			buf.Access(false, func(data []byte) error {
+				_ = data[0]
+				return nil
+			})
+		}
+	})
+	b.ReportMetric(float64(atomic.LoadInt64(&calls))/float64(b.N), "mprotects/op")
+}
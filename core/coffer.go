@@ -1,6 +1,7 @@
 package core
 
 import (
+	"crypto/rand"
 	"errors"
 	"sync"
 	"time"
@@ -72,11 +73,11 @@ func (s *Coffer) initialise() error {
 	s.Lock()
 	defer s.Unlock()
 
-	// Overwrite the old value with fresh random bytes.
-	if err := Scramble(s.left.Data()); err != nil {
+	// Overwrite the old value with fresh random bytes. These are always read from the system so that the key is unaffected by RandReader.
+	if _, err := rand.Read(s.left.Data()); err != nil {
 		return err
 	}
-	if err := Scramble(s.right.Data()); err != nil {
+	if _, err := rand.Read(s.right.Data()); err != nil {
 		return err
 	}
 
@@ -152,7 +153,7 @@ func (s *Coffer) rekey() error {
 	defer s.Unlock()
 
 	// Attain 32 bytes of fresh cryptographic buf32.
-	if err := Scramble(s.rand.Data()); err != nil {
+	if _, err := rand.Read(s.rand.Data()); err != nil {
 		return err
 	}
 
@@ -0,0 +1,41 @@
+// +build linux
+
+package core
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Flag from <linux/mman.h> asking mlock2(2) to lock pages only as they are faulted in.
+const mlockOnFault = 1 // MLOCK_ONFAULT
+
+// Discards the pages of a page-aligned region of memory with madvise(2) so that they stop occupying physical memory. If the region is locked, it is unlocked first and then locked again with mlock2(2) so that the pages are locked as they are faulted back in. Should that fail, the region is locked as usual before the error is returned.
+func releasePages(b []byte, locked bool) error {
+	if locked {
+		if err := unlockMemory(b); err != nil {
+			return err
+		}
+	}
+	err := unix.Madvise(b, unix.MADV_DONTNEED)
+	if !locked {
+		return err
+	}
+	if err == nil {
+		_, _, errno := unix.Syscall(
+			unix.SYS_MLOCK2,
+			uintptr(unsafe.Pointer(&b[0])),
+			uintptr(len(b)),
+			mlockOnFault,
+		)
+		if errno == 0 {
+			return nil
+		}
+		err = errno
+	}
+	if lerr := lockMemory(b); lerr != nil {
+		return lerr
+	}
+	return err
+}
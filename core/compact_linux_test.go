@@ -0,0 +1,86 @@
+// +build linux
+
+package core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompactResidency(t *testing.T) {
+	b, err := NewBuffer(8 * pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	defer b.Destroy()
+	Scramble(b.Data())
+	used := append([]byte(nil), b.Data()[:pageSize+1]...)
+
+	if err := b.CompactResidency(pageSize + 1); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if len(b.Data()) != 8*pageSize {
+		t.Error("buffer was resized")
+	}
+
+	// The used pages stay resident and the tail pages are released.
+	if ok, err := resident(b.inner[:2*pageSize]); err != nil || !ok {
+		t.Error("expected used pages to be resident;", err)
+	}
+	if ok, err := resident(b.inner[2*pageSize:]); err != nil || ok {
+		t.Error("expected tail pages not to be resident;", err)
+	}
+	if !bytes.Equal(b.Data()[:pageSize+1], used) {
+		t.Error("used data was modified")
+	}
+	if !bytes.Equal(b.Data()[2*pageSize:], make([]byte, 6*pageSize)) {
+		t.Error("released data was not zeroed")
+	}
+
+	// Writing to the tail faults the pages back in.
+	for i := 2 * pageSize; i < len(b.Data()); i += pageSize {
+		b.Data()[i] = 1
+	}
+	if ok, err := resident(b.inner); err != nil || !ok {
+		t.Error("expected all pages to be resident;", err)
+	}
+}
+
+func TestCompactResidencyErrors(t *testing.T) {
+	b, err := NewBuffer(pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Nothing beyond the used length fills a page.
+	if err := b.CompactResidency(pageSize); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if err := b.CompactResidency(pageSize + 1); err != ErrOutOfBounds {
+		t.Error("expected ErrOutOfBounds; got", err)
+	}
+	if err := b.CompactResidency(-1); err != ErrOutOfBounds {
+		t.Error("expected ErrOutOfBounds; got", err)
+	}
+	a, err := NewArena(4 * pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	defer a.Destroy()
+	c, err := a.NewBuffer(pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	if err := c.CompactResidency(0); err != ErrCompactUnsupported {
+		t.Error("expected ErrCompactUnsupported; got", err)
+	}
+
+	b.Freeze()
+	if err := b.CompactResidency(0); err != ErrBufferImmutable {
+		t.Error("expected ErrBufferImmutable; got", err)
+	}
+	b.Destroy()
+	if err := b.CompactResidency(0); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,8 @@
+// +build !linux
+
+package core
+
+// Pages can only be released on Linux.
+func releasePages(b []byte, locked bool) error {
+	return ErrAdviceUnsupported
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"errors"
+	"hash"
+	"io"
 	"runtime"
 	"unsafe"
 
@@ -11,6 +13,13 @@ import (
 	"golang.org/x/crypto/nacl/secretbox"
 )
 
+/*
+RandReader is the source of the random bytes used by Scramble, and so by everything that generates nonces, canaries, or random data. It defaults to crypto/rand.Reader.
+
+It exists so that tests can inject a deterministic or failing reader, and should not be changed in production code. It must not be replaced while other goroutines may be reading from it. The key used to protect Enclave objects is always generated by crypto/rand.Reader regardless of this value.
+*/
+var RandReader io.Reader = rand.Reader
+
 // Overhead is the size by which the ciphertext exceeds the plaintext.
 const Overhead int = secretbox.Overhead + 24 // auth + nonce
 
@@ -36,7 +45,7 @@ func Encrypt(plaintext, key []byte) ([]byte, error) {
 	// Allocate space for and generate a nonce value.
 	var nonce [24]byte
 	if err := Scramble(nonce[:]); err != nil {
-		Panic(err)
+		return nil, err
 	}
 
 	// Encrypt m and return the result.
@@ -68,26 +77,87 @@ func Decrypt(ciphertext, key []byte, output []byte) (int, error) {
 	var nonce [24]byte
 	Copy(nonce[:], ciphertext[:24])
 
-	// Decrypt and return the result.
-	m, ok := secretbox.Open(nil, ciphertext[24:], &nonce, k)
+	// Decrypt directly into the given output buffer and return the result.
+	m, ok := secretbox.Open(output[:0], ciphertext[24:], &nonce, k)
 	if ok { // Decryption successful.
-		Move(output[:cap(output)], m) // Move plaintext to given output buffer.
-		return len(m), nil            // Return length of decrypted plaintext.
+		return len(m), nil // Return length of decrypted plaintext.
 	}
 
 	// Decryption unsuccessful. Either the key was wrong or the authentication failed.
 	return 0, ErrDecryptionFailed
 }
 
+/*
+HMAC computes the keyed-hash message authentication code of a message under a given key using the hash function h, as defined in RFC 2104, and writes the tag to the start of a given buffer.
+
+The padded keys and the inner digest are kept inside a guarded Buffer that is destroyed before returning, and the hash states are reset so that they no longer depend on the key. The output buffer must be at least h().Size() bytes long.
+
+The size of the tag is returned.
+*/
+func HMAC(h func() hash.Hash, key, message, output []byte) (int, error) {
+	inner := h()
+
+	// Check the given output buffer can contain the tag.
+	if len(output) < inner.Size() {
+		return 0, ErrBufferTooSmall
+	}
+
+	// Allocate a guarded scratch region for the intermediate values.
+	scratch, err := NewBuffer((2 * inner.BlockSize()) + inner.Size())
+	if err != nil {
+		return 0, err
+	}
+	defer scratch.Destroy()
+
+	return hmacWithScratch(inner, h(), key, message, output, scratch.Data()), nil
+}
+
+// Computes an HMAC using the given scratch space, which is wiped before returning.
+func hmacWithScratch(inner, outer hash.Hash, key, message, output, scratch []byte) int {
+	bs, size := inner.BlockSize(), inner.Size()
+	ipad := scratch[:bs]
+	opad := scratch[bs : 2*bs]
+	digest := scratch[2*bs : (2*bs)+size]
+
+	// Keys longer than the block size are hashed first.
+	if len(key) > bs {
+		inner.Write(key)
+		inner.Sum(ipad[:0])
+		inner.Reset()
+	} else {
+		Copy(ipad, key)
+	}
+	Copy(opad, ipad)
+	for i := range ipad {
+		ipad[i] ^= 0x36
+		opad[i] ^= 0x5c
+	}
+
+	// tag = H(opad || H(ipad || message))
+	inner.Write(ipad)
+	inner.Write(message)
+	inner.Sum(digest[:0])
+	outer.Write(opad)
+	outer.Write(digest)
+	outer.Sum(output[:0])
+
+	// Clear the key-dependent state.
+	inner.Reset()
+	outer.Reset()
+	Wipe(scratch)
+
+	return size
+}
+
 // Hash implements a cryptographic hash function using Blake2b.
 func Hash(b []byte) []byte {
 	h := blake2b.Sum256(b)
 	return h[:]
 }
 
-// Scramble fills a given buffer with cryptographically-secure random bytes.
+// Scramble fills a given buffer with cryptographically-secure random bytes read from RandReader.
 func Scramble(buf []byte) error {
-	if _, err := rand.Read(buf); err != nil {
+	if _, err := io.ReadFull(RandReader, buf); err != nil {
 		return err
 	}
 
@@ -108,24 +178,60 @@ func Wipe(buf []byte) {
 	runtime.KeepAlive(buf)
 }
 
-// Copy is identical to Go's builtin copy function except the copying is done in constant time. This is to mitigate against side-channel attacks.
+/*
+Copy copies the bytes of src into dst, up to the length of the shorter of the two. The time it takes depends only on the lengths and alignment of the slices, never on the values being copied, so it does not leak the contents through a timing side channel.
+
+Like the builtin copy, the source and destination may overlap.
+*/
 func Copy(dst, src []byte) {
-	if len(dst) > len(src) {
-		subtle.ConstantTimeCopy(1, dst[:len(src)], src)
-	} else if len(dst) < len(src) {
-		subtle.ConstantTimeCopy(1, dst, src[:len(dst)])
-	} else {
-		subtle.ConstantTimeCopy(1, dst, src)
-	}
+	// The builtin's memmove does not branch on the data.
+	copy(dst, src)
 }
 
-// Move is identical to Copy except it wipes the source buffer after the copy operation is executed.
+/*
+Move is identical to Copy except it wipes the source buffer after the copy operation is executed.
+
+If the source and destination overlap, only the part of the source that was not overwritten by the copy is wiped.
+*/
 func Move(dst, src []byte) {
 	Copy(dst, src)
-	Wipe(src)
+
+	n := len(dst)
+	if len(src) < n {
+		n = len(src)
+	}
+	if n == 0 {
+		Wipe(src)
+		return
+	}
+
+	// Wipe the source, excluding the region that now holds the copied data.
+	d, s := uintptr(unsafe.Pointer(&dst[0])), uintptr(unsafe.Pointer(&src[0]))
+	if d+uintptr(n) <= s || s+uintptr(len(src)) <= d {
+		Wipe(src)
+		return
+	}
+	if d > s {
+		Wipe(src[:d-s])
+	}
+	if end := d + uintptr(n); end < s+uintptr(len(src)) {
+		Wipe(src[end-s:])
+	}
 }
 
-// Equal does a constant-time comparison of two byte slices. This is to mitigate against side-channel attacks.
+/*
+Equal does a constant-time comparison of two byte slices. This is to mitigate against side-channel attacks.
+
+The running time depends only on the length of x. If the lengths differ the function still inspects every byte of x before returning false, so the relationship between the two lengths is not revealed by an early exit.
+*/
 func Equal(x, y []byte) bool {
-	return subtle.ConstantTimeCompare(x, y) == 1
+	var v byte
+	for i := range x {
+		var c byte
+		if i < len(y) {
+			c = y[i]
+		}
+		v |= x[i] ^ c
+	}
+	return subtle.ConstantTimeByteEq(v, 0) == 1 && len(x) == len(y)
 }
@@ -2,7 +2,15 @@ package core
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	mrand "math/rand"
 	"testing"
 )
 
@@ -33,6 +41,27 @@ func TestCopy(t *testing.T) {
 	if !bytes.Equal(b, b2) {
 		t.Error("incorrect copying")
 	}
+
+	// overlapping, dst before src
+	d := []byte("0123456789")
+	Copy(d, d[3:])
+	if !bytes.Equal(d, []byte("3456789789")) {
+		t.Error("incorrect overlapping copy", string(d))
+	}
+
+	// overlapping, dst after src
+	d = []byte("0123456789")
+	Copy(d[3:], d)
+	if !bytes.Equal(d, []byte("0120123456")) {
+		t.Error("incorrect overlapping copy", string(d))
+	}
+
+	// identical
+	d = []byte("0123456789")
+	Copy(d, d)
+	if !bytes.Equal(d, []byte("0123456789")) {
+		t.Error("incorrect overlapping copy", string(d))
+	}
 }
 
 func TestMove(t *testing.T) {
@@ -45,6 +74,27 @@ func TestMove(t *testing.T) {
 	if !bytes.Equal(b, make([]byte, 32)) {
 		t.Error("src buffer was not wiped")
 	}
+
+	// overlapping, dst before src
+	d := []byte("0123456789")
+	Move(d, d[3:])
+	if !bytes.Equal(d, []byte("3456789\x00\x00\x00")) {
+		t.Error("incorrect overlapping move", d)
+	}
+
+	// overlapping, dst after src
+	d = []byte("0123456789")
+	Move(d[3:], d[:5])
+	if !bytes.Equal(d, []byte("\x00\x00\x000123489")) {
+		t.Error("incorrect overlapping move", d)
+	}
+
+	// identical
+	d = []byte("0123456789")
+	Move(d, d)
+	if !bytes.Equal(d, []byte("0123456789")) {
+		t.Error("incorrect overlapping move", d)
+	}
 }
 
 func TestCompare(t *testing.T) {
@@ -71,6 +121,26 @@ func TestCompare(t *testing.T) {
 	if Equal(b, c) {
 		t.Error("expected not equal")
 	}
+
+	// shorter candidate sharing a prefix
+	if Equal(b, b[:15]) {
+		t.Error("expected not equal")
+	}
+
+	// longer candidate sharing a prefix
+	d := make([]byte, 17)
+	copy(d, b)
+	if Equal(b, d) {
+		t.Error("expected not equal")
+	}
+
+	// empty values
+	if Equal(b, nil) || Equal(nil, b) {
+		t.Error("expected not equal")
+	}
+	if !Equal(nil, []byte{}) {
+		t.Error("expected equal")
+	}
 }
 
 func TestScramble(t *testing.T) {
@@ -89,6 +159,46 @@ func TestScramble(t *testing.T) {
 	}
 }
 
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("rand failure")
+}
+
+func TestRandReader(t *testing.T) {
+	defer func(r io.Reader) { RandReader = r }(RandReader)
+
+	// A failing reader should have its error propagated.
+	RandReader = failingReader{}
+	if err := Scramble(make([]byte, 32)); err == nil {
+		t.Error("expected error from Scramble")
+	}
+	if b, err := NewBuffer(32); err == nil || b != nil {
+		t.Error("expected error from NewBuffer; got", b, err)
+	}
+	if _, err := Encrypt([]byte("yellow submarine"), make([]byte, 32)); err == nil {
+		t.Error("expected error from Encrypt")
+	}
+
+	// A deterministic reader should give reproducible output.
+	RandReader = mrand.New(mrand.NewSource(1))
+	b := make([]byte, 32)
+	Scramble(b)
+	RandReader = mrand.New(mrand.NewSource(1))
+	c := make([]byte, 32)
+	Scramble(c)
+	if !bytes.Equal(b, c) {
+		t.Error("output not reproducible")
+	}
+	RandReader = mrand.New(mrand.NewSource(1))
+	x, _ := Encrypt([]byte("yellow submarine"), make([]byte, 32))
+	RandReader = mrand.New(mrand.NewSource(1))
+	y, _ := Encrypt([]byte("yellow submarine"), make([]byte, 32))
+	if !bytes.Equal(x, y) {
+		t.Error("ciphertext not reproducible")
+	}
+}
+
 func TestHash(t *testing.T) {
 	known := make(map[string]string)
 	known[""] = "DldRwCblQ7Loqy6wYJnaodHl30d3j3eH+qtFzfEv46g="
@@ -102,6 +212,48 @@ func TestHash(t *testing.T) {
 	}
 }
 
+func TestHMAC(t *testing.T) {
+	message := []byte("yellow submarine")
+	for _, h := range []func() hash.Hash{sha1.New, sha256.New, sha512.New} {
+		for _, length := range []int{0, 20, 64, 65, 200} {
+			key := make([]byte, length)
+			Scramble(key)
+
+			mac := hmac.New(h, key)
+			mac.Write(message)
+			expected := mac.Sum(nil)
+
+			output := make([]byte, h().Size())
+			n, err := HMAC(h, key, message, output)
+			if err != nil {
+				t.Error(err)
+			}
+			if n != len(expected) {
+				t.Error("incorrect tag length", n)
+			}
+			if !bytes.Equal(output, expected) {
+				t.Error("tag does not match crypto/hmac")
+			}
+
+			// Check that the scratch space is wiped afterwards.
+			scratch := make([]byte, (2*h().BlockSize())+h().Size())
+			Wipe(output)
+			hmacWithScratch(h(), h(), key, message, output, scratch)
+			if !bytes.Equal(output, expected) {
+				t.Error("tag does not match crypto/hmac")
+			}
+			if !bytes.Equal(scratch, make([]byte, len(scratch))) {
+				t.Error("scratch space was not wiped")
+			}
+		}
+	}
+
+	// Output buffer too small.
+	if _, err := HMAC(sha256.New, []byte("key"), message, make([]byte, 31)); err != ErrBufferTooSmall {
+		t.Error("expected ErrBufferTooSmall; got", err)
+	}
+}
+
 func TestWipe(t *testing.T) {
 	b := make([]byte, 32)
 	Scramble(b)
@@ -219,3 +371,28 @@ func TestEncryptDecrypt(t *testing.T) {
 		t.Error("expected error with invalid key; got", err)
 	}
 }
+
+func TestDecryptInPlace(t *testing.T) {
+	m := make([]byte, 64)
+	Scramble(m)
+	k := make([]byte, 32)
+	Scramble(k)
+	x, err := Encrypt(m, k)
+	if err != nil {
+		t.Error("expected no errors; got", err)
+	}
+
+	// The plaintext should never be held in an intermediate heap slice.
+	out := make([]byte, len(m))
+	allocs := testing.AllocsPerRun(10, func() {
+		if _, err := Decrypt(x, k, out); err != nil {
+			t.Error("unexpected error:", err)
+		}
+	})
+	if allocs != 0 {
+		t.Error("expected no allocations; got", allocs)
+	}
+	if !bytes.Equal(m, out) {
+		t.Error("decrypted plaintext does not match original")
+	}
+}
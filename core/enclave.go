@@ -17,6 +17,9 @@ func init() {
 // ErrNullEnclave is returned when attempting to construct an enclave of size less than one.
 var ErrNullEnclave = errors.New("<memguard::core::ErrNullEnclave> enclave size must be greater than zero")
 
+// ErrInvalidCiphertext is returned when attempting to reconstruct an enclave from a ciphertext that is too short to hold a nonce, an authenticator, and at least one byte of data.
+var ErrInvalidCiphertext = errors.New("<memguard::core::ErrInvalidCiphertext> ciphertext is too short to be an enclave")
+
 /*
 Enclave is a sealed and encrypted container for sensitive data.
 */
@@ -69,12 +72,11 @@ func Seal(b *Buffer) (*Enclave, error) {
 	b.Melt() // Make the buffer mutable so that we can wipe it.
 
 	// Construct the Enclave from the Buffer's data.
-	e, err := func() (*Enclave, error) {
-		b.RLock() // Attain a read lock.
-		defer b.RUnlock()
-		return NewEnclave(b.Data())
-	}()
-	if err != nil {
+	var e *Enclave
+	if err := b.Access(false, func(data []byte) (err error) {
+		e, err = NewEnclave(data)
+		return err
+	}); err != nil {
 		return nil, err
 	}
 
@@ -116,9 +118,48 @@ func Open(e *Enclave) (*Buffer, error) {
 	return b, nil
 }
 
+/*
+OpenInto decrypts an Enclave directly into the start of a given slice, which must be at least EnclaveSize bytes long. The given Enclave is left untouched and may be reused.
+
+The authenticator is verified before anything is written, so the slice is left unchanged if ErrDecryptionFailed is returned.
+*/
+func OpenInto(e *Enclave, buf []byte) error {
+	if len(buf) < EnclaveSize(e) {
+		return ErrBufferTooSmall
+	}
+
+	// Grab a view of the key.
+	k, err := key.View()
+	if err != nil {
+		return err
+	}
+	defer k.Destroy()
+
+	// Decrypt the enclave into the slice.
+	_, err = Decrypt(e.ciphertext, k.Data(), buf[:EnclaveSize(e)])
+	return err
+}
+
 /*
 EnclaveSize returns the number of bytes of plaintext data stored inside an Enclave.
 */
 func EnclaveSize(e *Enclave) int {
 	return len(e.ciphertext) - Overhead
 }
+
+/*
+EnclaveCiphertext returns a copy of the authenticated ciphertext stored inside an Enclave. It can only be decrypted during the current session, since the key used to seal it is not persisted.
+*/
+func EnclaveCiphertext(e *Enclave) []byte {
+	return append([]byte(nil), e.ciphertext...)
+}
+
+/*
+EnclaveFromCiphertext reconstructs an Enclave from a ciphertext previously returned by EnclaveCiphertext. The ciphertext is copied, and its authenticity is only verified when the Enclave is opened.
+*/
+func EnclaveFromCiphertext(ciphertext []byte) (*Enclave, error) {
+	if len(ciphertext) <= Overhead {
+		return nil, ErrInvalidCiphertext
+	}
+	return &Enclave{append([]byte(nil), ciphertext...)}, nil
+}
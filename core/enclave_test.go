@@ -141,3 +141,76 @@ func TestEnclaveSize(t *testing.T) {
 		t.Error("invalid enclave size")
 	}
 }
+
+func TestEnclaveCiphertext(t *testing.T) {
+	e, err := NewEnclave([]byte("yellow submarine"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	// The ciphertext is copied out.
+	ciphertext := EnclaveCiphertext(e)
+	if !bytes.Equal(ciphertext, e.ciphertext) {
+		t.Error("ciphertext does not match")
+	}
+	ciphertext[0] ^= 0xff
+	if bytes.Equal(ciphertext, e.ciphertext) {
+		t.Error("ciphertext not copied")
+	}
+	ciphertext[0] ^= 0xff
+
+	// Reconstruct the enclave and open it.
+	f, err := EnclaveFromCiphertext(ciphertext)
+	if err != nil {
+		t.Error(err)
+	}
+	ciphertext[0] ^= 0xff
+	buf, err := Open(f)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(buf.Data(), []byte("yellow submarine")) {
+		t.Error("decrypted data does not match original")
+	}
+	buf.Destroy()
+
+	// Ciphertexts too short to hold any data are rejected.
+	for _, n := range []int{0, 1, Overhead} {
+		if f, err := EnclaveFromCiphertext(make([]byte, n)); err != ErrInvalidCiphertext || f != nil {
+			t.Error("expected ErrInvalidCiphertext; got", err)
+		}
+	}
+	if _, err := EnclaveFromCiphertext(make([]byte, Overhead+1)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOpenInto(t *testing.T) {
+	e, err := NewEnclave([]byte("yellow submarine"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Decrypt into a larger slice.
+	buf := make([]byte, 20)
+	if err := OpenInto(e, buf); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(buf, append([]byte("yellow submarine"), 0, 0, 0, 0)) {
+		t.Error("decrypted data does not match original")
+	}
+
+	if err := OpenInto(e, make([]byte, 15)); err != ErrBufferTooSmall {
+		t.Error("expected ErrBufferTooSmall; got", err)
+	}
+
+	// Tampered ciphertexts leave the slice unchanged.
+	e.ciphertext[len(e.ciphertext)-1] ^= 0xff
+	buf = make([]byte, 16)
+	if err := OpenInto(e, buf); err != ErrDecryptionFailed {
+		t.Error("expected decryption error; got", err)
+	}
+	if !bytes.Equal(buf, make([]byte, 16)) {
+		t.Error("slice modified on failure")
+	}
+}
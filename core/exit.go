@@ -24,6 +24,7 @@ func Purge() {
 
 		// Get a snapshot of existing Buffers.
 		snapshot := buffers.flush()
+		infof("purging %d buffers", len(snapshot))
 
 		// Destroy them, performing the usual sanity checks.
 		for _, b := range snapshot {
@@ -36,11 +37,11 @@ func Purge() {
 				// buffer destroy failed; wipe instead
 				b.Lock()
 				defer b.Unlock()
-				if !b.mutable {
+				if !b.mutable || b.noaccess {
 					if err := memcall.Protect(b.inner, memcall.ReadWrite()); err != nil {
 						// couldn't change it to mutable; we can't wipe it! (could this happen?)
 						// not sure what we can do at this point, just warn and move on
-						fmt.Fprintf(os.Stderr, "!WARNING: failed to wipe immutable data at address %p", &b.data)
+						warnf("failed to wipe immutable data at address %p", &b.data)
 						continue // wipe in subprocess?
 					}
 				}
@@ -59,6 +60,30 @@ func Purge() {
 	}
 }
 
+/*
+DestroyAllExcept destroys every existing Buffer other than those given, which are left untouched. Unlike Purge, the session key is kept, so existing Enclave objects remain decryptable. This is useful for rekeying or shutdown paths that must preserve specific Buffers while wiping everything else.
+*/
+func DestroyAllExcept(keep ...*Buffer) {
+	// The Buffers holding the session key are always kept.
+	key.RLock()
+	keep = append(keep, key.left, key.right, key.rand)
+	key.RUnlock()
+
+	// Destroy everything else, performing the usual sanity checks.
+	for _, b := range buffers.copy() {
+		kept := false
+		for _, k := range keep {
+			if b == k {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			b.Destroy()
+		}
+	}
+}
+
 /*
 Exit terminates the process with a specified exit code but securely wipes and cleans up sensitive data before doing so.
 */
@@ -75,6 +75,51 @@ func TestPurge(t *testing.T) {
 	buffers.remove(b)
 }
 
+func TestDestroyAllExcept(t *testing.T) {
+	enclave, err := NewEnclave([]byte("yellow submarine"))
+	if err != nil {
+		t.Error(err)
+	}
+	var all []*Buffer
+	for i := 0; i < 5; i++ {
+		b, err := NewBuffer(32)
+		if err != nil {
+			t.Error(err)
+		}
+		all = append(all, b)
+	}
+
+	DestroyAllExcept(all[1], all[3])
+
+	for i, b := range all {
+		if b.Alive() != (i == 1 || i == 3) {
+			t.Error("unexpected state for buffer", i)
+		}
+	}
+	buffers.RLock()
+	if len(buffers.list) != 5 {
+		t.Error("expected kept buffers and key to remain in list; got", len(buffers.list))
+	}
+	buffers.RUnlock()
+
+	// The session key is kept.
+	if !key.left.Alive() || !key.right.Alive() || !key.rand.Alive() {
+		t.Error("session key was destroyed")
+	}
+	b, err := Open(enclave)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !bytes.Equal(b.Data(), []byte("yellow submarine")) {
+		t.Error("enclave not decryptable")
+	}
+
+	DestroyAllExcept()
+	if b.Alive() || all[1].Alive() || all[3].Alive() {
+		t.Error("expected every buffer to be destroyed")
+	}
+}
+
 func TestPanic(t *testing.T) {
 	// Call Panic and check if it panics.
 	if !panics(func() {
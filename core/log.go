@@ -0,0 +1,51 @@
+package core
+
+import (
+	"sync"
+)
+
+/*
+Logger receives messages about events that do not cause an operation to fail but that may still be of interest, such as a protection that could not be applied. Messages never include the contents of any Buffer.
+*/
+type Logger interface {
+	Warnf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+}
+
+// Discards all messages.
+type nopLogger struct{}
+
+func (nopLogger) Warnf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{}) {}
+
+var (
+	logger      Logger = nopLogger{}
+	loggerMutex sync.RWMutex
+)
+
+/*
+SetLogger sets the Logger that receives internal messages. By default, and if l is nil, messages are discarded.
+*/
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+	logger = l
+}
+
+func warnf(format string, args ...interface{}) {
+	loggerMutex.RLock()
+	l := logger
+	loggerMutex.RUnlock()
+	l.Warnf(format, args...)
+}
+
+func infof(format string, args ...interface{}) {
+	loggerMutex.RLock()
+	l := logger
+	loggerMutex.RUnlock()
+	l.Infof(format, args...)
+}
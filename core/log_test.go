@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type captureLogger struct {
+	sync.Mutex
+	warnings []string
+	infos    []string
+}
+
+func (l *captureLogger) Warnf(format string, args ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func (l *captureLogger) Infof(format string, args ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.infos = append(l.infos, fmt.Sprintf(format, args...))
+}
+
+func TestSetLogger(t *testing.T) {
+	l := new(captureLogger)
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	// Binding to a node that cannot exist fails and falls back to the default policy.
+	b, err := NewBufferOnNode(32, -1)
+	if err != nil {
+		t.Error(err)
+	}
+	b.Destroy()
+	if len(l.warnings) != 1 || !strings.Contains(l.warnings[0], "NUMA node -1") {
+		t.Error("expected a warning; got", l.warnings)
+	}
+
+	// Messages are discarded once the logger is removed.
+	SetLogger(nil)
+	b, err = NewBufferOnNode(32, -1)
+	if err != nil {
+		t.Error(err)
+	}
+	b.Destroy()
+	if len(l.warnings) != 1 {
+		t.Error("unexpected warning", l.warnings)
+	}
+}
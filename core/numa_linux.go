@@ -0,0 +1,39 @@
+// +build linux
+
+package core
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Memory policy constants from <linux/mempolicy.h>.
+const (
+	mpolBind   = 2      // MPOL_BIND
+	mpolMFMove = 1 << 1 // MPOL_MF_MOVE
+	maxNodes   = 1024   // Number of nodes representable in a node mask.
+)
+
+// Binds a region of memory to a given NUMA node using mbind(2), moving any pages already allocated elsewhere.
+func bindToNode(b []byte, node int) error {
+	if node < 0 || node >= maxNodes {
+		return unix.EINVAL
+	}
+
+	var mask [maxNodes / 64]uint64
+	mask[node/64] |= 1 << uint(node%64)
+
+	if _, _, errno := unix.Syscall6(
+		unix.SYS_MBIND,
+		uintptr(unsafe.Pointer(&b[0])),
+		uintptr(len(b)),
+		mpolBind,
+		uintptr(unsafe.Pointer(&mask[0])),
+		maxNodes,
+		mpolMFMove,
+	); errno != 0 {
+		return errno
+	}
+	return nil
+}
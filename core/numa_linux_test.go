@@ -0,0 +1,55 @@
+// +build linux
+
+package core
+
+import (
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestNewBufferOnNode(t *testing.T) {
+	b, err := NewBufferOnNode(32, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	defer b.Destroy()
+	if len(b.Data()) != 32 || !b.alive || !b.mutable {
+		t.Error("buffer constructed incorrectly")
+	}
+	if len(b.memory) != roundToPageSize(32)+(2*pageSize) {
+		t.Error("allocated incorrect length of memory")
+	}
+
+	// Query the policy applied to the data region.
+	var mode int32
+	var mask [maxNodes / 64]uint64
+	if _, _, errno := unix.Syscall6(
+		unix.SYS_GET_MEMPOLICY,
+		uintptr(unsafe.Pointer(&mode)),
+		uintptr(unsafe.Pointer(&mask[0])),
+		maxNodes,
+		uintptr(unsafe.Pointer(&b.Data()[0])),
+		1<<1, // MPOL_F_ADDR
+		0,
+	); errno != 0 {
+		t.Skip("NUMA is unavailable:", errno)
+	}
+	if mode != mpolBind {
+		t.Skip("NUMA binding was not applied; policy", mode)
+	}
+	if mask[0]&1 != 1 {
+		t.Error("memory is not bound to node 0", mask)
+	}
+
+	// Invalid nodes fall back to the default policy.
+	c, err := NewBufferOnNode(32, -1)
+	if err != nil {
+		t.Error(err)
+	}
+	if !c.alive {
+		t.Error("expected fallback allocation")
+	}
+	c.Destroy()
+}
@@ -0,0 +1,10 @@
+// +build !linux
+
+package core
+
+import "errors"
+
+// Binding memory to a NUMA node is only supported on Linux.
+func bindToNode(b []byte, node int) error {
+	return errors.New("<memguard::core> NUMA binding is not supported on this platform")
+}
@@ -0,0 +1,104 @@
+package core
+
+import "errors"
+
+// ErrSharedSize is returned when attaching to shared memory whose size does not match the requested size of the Buffer.
+var ErrSharedSize = errors.New("<memguard::core::ErrSharedSize> shared memory does not match the size of the buffer")
+
+// ErrSharedBuffer is returned when attempting to change the layout of a Buffer whose data is shared with other processes.
+var ErrSharedBuffer = errors.New("<memguard::core::ErrSharedBuffer> the layout of a shared buffer cannot be changed")
+
+// Length of the header after the pages of a shared memory file, which records the size of the Buffer it was created for.
+const sharedHeaderSize = 8
+
+/*
+NewSharedBuffer is identical to NewBuffer except that the inner region is backed by an anonymous memory file, created with memfd_create(2), that is mapped with MAP_SHARED. The descriptor of the file is returned so that it can be passed to another process, such as a child started with os/exec through ExtraFiles, which can then map the same memory with AttachSharedBuffer. The guard pages are private to each process.
+
+The descriptor is owned by the Buffer and is closed when it is destroyed. It is created with the close-on-exec flag set. This is only supported on Linux.
+*/
+func NewSharedBuffer(size int) (*Buffer, uintptr, error) {
+	if size < 1 {
+		return nil, 0, ErrNullBuffer
+	}
+
+	f, err := createShared(size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b, err := newSharedBuffer(size, f.Fd(), true)
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	b.file = f
+
+	return b, f.Fd(), nil
+}
+
+/*
+AttachSharedBuffer maps the shared memory created by NewSharedBuffer in another process into a new Buffer of the same size, which is locked and surrounded by guard pages of its own. ErrSharedSize is returned if the memory was created for a Buffer of a different size, even one that occupies the same number of pages, as the data would then be placed differently within them.
+
+The descriptor is not taken over by the Buffer and may be closed once this function returns. Destroying any of the Buffers that share the memory wipes the data for all of them. This is only supported on Linux.
+*/
+func AttachSharedBuffer(fd uintptr, size int) (*Buffer, error) {
+	if size < 1 {
+		return nil, ErrNullBuffer
+	}
+	return newSharedBuffer(size, fd, false)
+}
+
+// Allocates a Buffer whose inner region is mapped from a shared memory file, generating a new canary if create is true and otherwise using the one already present.
+func newSharedBuffer(size int, fd uintptr, create bool) (*Buffer, error) {
+	var err error
+
+	b := new(Buffer)
+
+	// Allocate the total needed memory and map the shared file over the inner region.
+	b.memory, err = allocMemory((2 * pageSize) + roundToPageSize(size))
+	if err != nil {
+		Panic(err)
+	}
+	b.layout(size)
+	if err := mapShared(b.inner, fd, size); err != nil {
+		if err := freeMemory(b.memory); err != nil {
+			Panic(err)
+		}
+		return nil, err
+	}
+	b.shared = true
+
+	// Generate the canary value if this is a new region.
+	if create {
+		if err := Scramble(b.canary); err != nil {
+			if err := freeMemory(b.memory); err != nil {
+				Panic(err)
+			}
+			return nil, err
+		}
+	}
+
+	// Lock the pages that hold sensitive data.
+	if err := lockMemory(b.inner); err != nil {
+		Panic(err)
+	}
+	addLockedBytes(len(b.inner))
+
+	// Protect the memory and make the Buffer available.
+	b.activate()
+
+	return b, nil
+}
+
+// Unmaps the memory of a shared Buffer without wiping the shared region, and closes the shared memory file if it is owned by the Buffer.
+func (b *Buffer) unmapShared() error {
+	if err := unmapMemory(b.memory); err != nil {
+		return err
+	}
+	if b.file != nil {
+		f := b.file
+		b.file = nil
+		return f.Close()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// +build linux
+
+package core
+
+import (
+	"encoding/binary"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Creates an anonymous memory file using memfd_create(2) that holds the pages of a Buffer of a given size, followed by a header recording the size so that it can be checked when the memory is attached.
+func createShared(size int) (*os.File, error) {
+	fd, err := unix.MemfdCreate("memguard", unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	var header [sharedHeaderSize]byte
+	binary.BigEndian.PutUint64(header[:], uint64(size))
+	if err := unix.Ftruncate(fd, int64(roundToPageSize(size)+sharedHeaderSize)); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	if _, err := unix.Pwrite(fd, header[:], int64(roundToPageSize(size))); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), "memguard"), nil
+}
+
+// Maps a shared memory file over a page-aligned region of memory, which must be exactly the size of the pages in the file, after checking that the file was created for a Buffer of the given size.
+func mapShared(b []byte, fd uintptr, size int) error {
+	var stat unix.Stat_t
+	if err := unix.Fstat(int(fd), &stat); err != nil {
+		return err
+	}
+	if stat.Size != int64(len(b)+sharedHeaderSize) {
+		return ErrSharedSize
+	}
+	var header [sharedHeaderSize]byte
+	n, err := unix.Pread(int(fd), header[:], int64(len(b)))
+	if err != nil {
+		return err
+	}
+	if n != sharedHeaderSize || binary.BigEndian.Uint64(header[:]) != uint64(size) {
+		return ErrSharedSize
+	}
+
+	if _, _, errno := unix.Syscall6(
+		unix.SYS_MMAP,
+		uintptr(unsafe.Pointer(&b[0])),
+		uintptr(len(b)),
+		unix.PROT_READ|unix.PROT_WRITE,
+		unix.MAP_SHARED|unix.MAP_FIXED,
+		fd,
+		0,
+	); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Unmaps a region of memory without modifying it.
+func unmapMemory(b []byte) error {
+	return unix.Munmap(b)
+}
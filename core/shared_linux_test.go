@@ -0,0 +1,78 @@
+// +build linux
+
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSharedBuffer(t *testing.T) {
+	b, fd, err := NewSharedBuffer(32)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(b.Data()) != 32 || !b.alive || !b.mutable || !b.shared {
+		t.Error("buffer constructed incorrectly")
+	}
+	Copy(b.Data(), []byte("yellow submarine yellow submarin"))
+
+	// Attach a second view of the same memory.
+	c, err := AttachSharedBuffer(fd, 32)
+	if err != nil {
+		t.Error(err)
+	}
+	if &c.Data()[0] == &b.Data()[0] {
+		t.Error("expected a separate mapping")
+	}
+	if !bytes.Equal(c.Data(), b.Data()) || !bytes.Equal(c.canary, b.canary) {
+		t.Error("shared memory not visible through attached buffer")
+	}
+	c.Data()[0] = 'Y'
+	if b.Data()[0] != 'Y' {
+		t.Error("writes not visible through original buffer")
+	}
+
+	// The layout of the region cannot be changed.
+	if err := b.Grow(1); err != ErrSharedBuffer {
+		t.Error("expected ErrSharedBuffer; got", err)
+	}
+	if _, err := c.Consume(1); err != ErrSharedBuffer {
+		t.Error("expected ErrSharedBuffer; got", err)
+	}
+
+	// The size must match exactly, even if it occupies the same number of pages.
+	for _, size := range []int{31, 33, pageSize, pageSize + 1, 2 * pageSize} {
+		if d, err := AttachSharedBuffer(fd, size); err != ErrSharedSize || d != nil {
+			t.Error("expected ErrSharedSize; got", err)
+		}
+	}
+	if _, err := AttachSharedBuffer(fd, 0); err != ErrNullBuffer {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+
+	// Destroying a view wipes the data for both but leaves the other intact.
+	if err := c.destroy(); err != nil {
+		t.Error(err)
+	}
+	buffers.remove(c)
+	if !bytes.Equal(b.Data(), make([]byte, 32)) {
+		t.Error("shared data not wiped")
+	}
+	if err := b.destroy(); err != nil {
+		t.Error(err)
+	}
+	buffers.remove(b)
+
+	// The descriptor is closed with the original.
+	var stat unix.Stat_t
+	if err := unix.Fstat(int(fd), &stat); err != unix.EBADF {
+		t.Error("expected descriptor to be closed; got", err)
+	}
+
+	if _, _, err := NewSharedBuffer(0); err != ErrNullBuffer {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+}
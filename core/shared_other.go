@@ -0,0 +1,25 @@
+// +build !linux
+
+package core
+
+import (
+	"errors"
+	"os"
+)
+
+var errSharedUnsupported = errors.New("<memguard::core> shared memory is not supported on this platform")
+
+// Shared memory is only supported on Linux.
+func createShared(size int) (*os.File, error) {
+	return nil, errSharedUnsupported
+}
+
+// Shared memory is only supported on Linux.
+func mapShared(b []byte, fd uintptr, size int) error {
+	return errSharedUnsupported
+}
+
+// Shared memory is only supported on Linux.
+func unmapMemory(b []byte) error {
+	return errSharedUnsupported
+}
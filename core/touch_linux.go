@@ -0,0 +1,28 @@
+// +build linux
+
+package core
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Reports whether every page of a page-aligned region of memory is resident using mincore(2).
+func resident(b []byte) (bool, error) {
+	vec := make([]byte, (len(b)+pageSize-1)/pageSize)
+	if _, _, errno := unix.Syscall(
+		unix.SYS_MINCORE,
+		uintptr(unsafe.Pointer(&b[0])),
+		uintptr(len(b)),
+		uintptr(unsafe.Pointer(&vec[0])),
+	); errno != 0 {
+		return false, errno
+	}
+	for _, v := range vec {
+		if v&1 == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,82 @@
+// +build linux
+
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestTouch(t *testing.T) {
+	b, err := NewBuffer(3 * pageSize)
+	if err != nil {
+		t.Error(err)
+	}
+	Scramble(b.Data())
+	data := append([]byte(nil), b.Data()...)
+
+	if err := b.Touch(); err != nil {
+		t.Error(err)
+	}
+	if ok, err := resident(b.inner); err != nil || !ok {
+		t.Error("expected all pages to be resident;", err)
+	}
+	if !bytes.Equal(b.Data(), data) {
+		t.Error("touch modified the data")
+	}
+
+	// Inaccessible buffers can be touched.
+	if err := b.Protect(false, false); err != nil {
+		t.Error(err)
+	}
+	if err := b.Touch(); err != nil {
+		t.Error(err)
+	}
+	if err := b.Protect(true, true); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(b.Data(), data) {
+		t.Error("touch modified the data")
+	}
+
+	b.Destroy()
+	if err := b.Touch(); err != ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestResident(t *testing.T) {
+	memory, err := unix.Mmap(-1, 0, 4*pageSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		t.Error(err)
+	}
+	defer unix.Munmap(memory)
+
+	// Fresh anonymous memory is not backed until it is used.
+	if ok, err := resident(memory); err != nil || ok {
+		t.Error("expected untouched memory not to be resident;", err)
+	}
+	for i := 0; i < len(memory); i += pageSize {
+		memory[i] = 1
+	}
+	if ok, err := resident(memory); err != nil || !ok {
+		t.Error("expected touched memory to be resident;", err)
+	}
+}
+
+func TestVerifyLockOnCreateLinux(t *testing.T) {
+	SetVerifyLockOnCreate(true)
+	defer SetVerifyLockOnCreate(false)
+
+	// Locked memory is faulted in, so verification passes.
+	b, err := NewBuffer(3 * pageSize)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.Alive() || len(b.Data()) != 3*pageSize {
+		t.Error("invalid buffer")
+	}
+	b.Destroy()
+}
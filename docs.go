@@ -74,6 +74,9 @@ Several functions exist to make the mass purging of data very easy. It is recomm
 	// Purge the session when returning from the main function of your program
 	defer memguard.Purge()
 
+	// Wipe everything before an unexpected panic crashes the program
+	defer memguard.GuardPanics()
+
 	// Use the safe variants of exit functions provided in the stdlib
 	memguard.SafeExit(1)
 	memguard.SafePanic(err)
@@ -51,6 +51,20 @@ func (e *Enclave) Open() (*LockedBuffer, error) {
 	return newBuffer(b), nil
 }
 
+/*
+CopyFromEnclave decrypts an Enclave directly into an existing LockedBuffer, so that no separate buffer needs to be allocated to hold the plaintext. This is useful when LockedBuffers are reused. The size of the LockedBuffer must be exactly the size of the data stored within the Enclave, which is left untouched and may be reused.
+
+ErrInvalidLength is returned if the sizes differ, and core.ErrDecryptionFailed is returned if the Enclave could not be authenticated, in which case the LockedBuffer is left unchanged. An error is also returned if the LockedBuffer is immutable or has been destroyed.
+*/
+func (b *LockedBuffer) CopyFromEnclave(e *Enclave) error {
+	return b.Access(true, func(data []byte) error {
+		if len(data) != e.Size() {
+			return ErrInvalidLength
+		}
+		return core.OpenInto(e.Enclave, data)
+	})
+}
+
 /*
 Size returns the number of bytes of data stored within an Enclave.
 */
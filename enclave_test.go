@@ -89,3 +89,61 @@ func panics(fn func()) (panicked bool) {
 	fn()
 	return
 }
+
+func TestCopyFromEnclave(t *testing.T) {
+	b := NewBuffer(16)
+	defer b.Destroy()
+
+	// The same buffer can be reused for several enclaves.
+	for _, secret := range []string{"yellow submarine", "0123456789abcdef"} {
+		e := NewEnclave([]byte(secret))
+		if err := b.CopyFromEnclave(e); err != nil {
+			t.Error("unexpected error;", err)
+		}
+		if !equalTo(b, []byte(secret)) {
+			t.Error("data does not match")
+		}
+	}
+
+	// Sizes must match exactly.
+	for _, secret := range []string{"yellow", "yellow submarine!"} {
+		if err := b.CopyFromEnclave(NewEnclave([]byte(secret))); err != ErrInvalidLength {
+			t.Error("expected ErrInvalidLength; got", err)
+		}
+	}
+
+	// Tampered enclaves leave the buffer unchanged.
+	ciphertext := core.EnclaveCiphertext(NewEnclave([]byte("yellow submarine")).Enclave)
+	ciphertext[30] ^= 0xff
+	f, err := core.EnclaveFromCiphertext(ciphertext)
+	if err != nil {
+		t.Error("unexpected error;", err)
+	}
+	e := &Enclave{f}
+	if err := b.CopyFromEnclave(e); err != core.ErrDecryptionFailed {
+		t.Error("expected decryption error; got", err)
+	}
+	if !equalTo(b, []byte("0123456789abcdef")) {
+		t.Error("buffer modified on failure")
+	}
+
+	// Flags are honoured.
+	e = NewEnclave([]byte("yellow submarine"))
+	b.Freeze()
+	if err := b.CopyFromEnclave(e); err != core.ErrBufferImmutable {
+		t.Error("expected ErrBufferImmutable; got", err)
+	}
+	b.Melt()
+	b.Protect(false, true)
+	if err := b.CopyFromEnclave(e); err != nil {
+		t.Error("unexpected error;", err)
+	}
+	b.Protect(true, true)
+	if !equalTo(b, []byte("yellow submarine")) {
+		t.Error("data does not match")
+	}
+	b.Destroy()
+	if err := b.CopyFromEnclave(e); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,33 @@
+package memguard
+
+import (
+	"math"
+)
+
+/*
+ShannonEntropy estimates the entropy of the data in a LockedBuffer in bits per byte, from the frequency of each byte value. The result ranges from 0, for data consisting of a single repeated byte, to 8, for data in which every byte value occurs equally often.
+
+This is a sanity check for catching obviously weak secrets, such as a key that was never filled in or that repeats a short pattern, and not a measure of security: data with a high estimate may still be predictable. The estimate is only meaningful for reasonably large buffers, since n bytes can contain at most n distinct values.
+
+The LockedBuffer is made readable for the duration of the calculation if it has been made inaccessible, and the temporary frequency counts are wiped afterwards. core.ErrBufferExpired is returned if it has been destroyed.
+*/
+func (b *LockedBuffer) ShannonEntropy() (float64, error) {
+	var entropy float64
+	err := b.Access(false, func(data []byte) error {
+		var counts [256]int
+		for _, c := range data {
+			counts[c]++
+		}
+
+		n := float64(len(data))
+		for i, c := range counts {
+			if c > 0 {
+				p := float64(c) / n
+				entropy -= p * math.Log2(p)
+			}
+			counts[i] = 0
+		}
+		return nil
+	})
+	return entropy, err
+}
@@ -0,0 +1,55 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestShannonEntropy(t *testing.T) {
+	cases := []struct {
+		data     []byte
+		min, max float64
+	}{
+		{make([]byte, 4096), 0, 0},
+		{bytes.Repeat([]byte("a"), 7), 0, 0},
+		{bytes.Repeat([]byte("ab"), 512), 1, 1},
+		{bytes.Repeat([]byte("abcd"), 256), 2, 2},
+		{[]byte("yellow submarine"), 3.5, 4},
+	}
+	for _, c := range cases {
+		b := NewBufferFromBytes(append([]byte(nil), c.data...))
+		e, err := b.ShannonEntropy()
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if e < c.min || e > c.max {
+			t.Error("entropy", e, "not in range", c.min, c.max)
+		}
+		b.Destroy()
+	}
+
+	// Every byte value occurring equally often gives the maximum.
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	b := NewBufferFromBytes(all)
+	if e, err := b.ShannonEntropy(); err != nil || e != 8 {
+		t.Error("expected entropy of 8; got", e, err)
+	}
+	b.Destroy()
+
+	// Random data is close to the maximum.
+	b = NewBufferRandom(65536)
+	b.Protect(false, false)
+	if e, err := b.ShannonEntropy(); err != nil || e < 7.9 {
+		t.Error("expected high entropy; got", e, err)
+	}
+
+	b.Destroy()
+	if _, err := b.ShannonEntropy(); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,80 @@
+package memguard
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+/*
+EncryptToFile seals the contents of a LockedBuffer under a 32 byte key, as SealWithMetadata does with no metadata, and writes the serialized result to a file that only its owner can read or write. Only ciphertext is ever written to disk. The file is first written in full to a temporary file in the same directory and then renamed over the destination, so an existing file is either replaced entirely or left untouched. The LockedBuffer is left unchanged; see EncryptToFileAndDestroy.
+
+The contents can be recovered with DecryptFromFile. An error is returned if the key is not 32 bytes long, if either LockedBuffer has been destroyed, or if the file could not be written.
+*/
+func EncryptToFile(path string, b, key *LockedBuffer) error {
+	s, err := SealWithMetadata(b, key, nil)
+	if err != nil {
+		return err
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+/*
+EncryptToFileAndDestroy behaves like EncryptToFile, but destroys the LockedBuffer once its contents have been written. The LockedBuffer is left untouched if an error is returned, so that its contents are not lost.
+*/
+func EncryptToFileAndDestroy(path string, b, key *LockedBuffer) error {
+	if err := EncryptToFile(path, b, key); err != nil {
+		return err
+	}
+	b.Destroy()
+	return nil
+}
+
+/*
+DecryptFromFile reads a file written by EncryptToFile and decrypts its contents directly into a new immutable LockedBuffer.
+
+ErrInvalidSealedData is returned if the file is malformed, and core.ErrDecryptionFailed is returned if the key is incorrect or the file has been modified. An error is also returned if the file could not be read.
+*/
+func DecryptFromFile(path string, key *LockedBuffer) (*LockedBuffer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return newNullBuffer(), err
+	}
+	var s SealedData
+	if err := s.UnmarshalBinary(data); err != nil {
+		return newNullBuffer(), err
+	}
+	return s.Open(key)
+}
+
+// Writes data to a temporary file next to path with owner-only permissions, and then renames it to path.
+func writeFileAtomic(path string, data []byte) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = f.Chmod(0600); err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
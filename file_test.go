@@ -0,0 +1,112 @@
+package memguard
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestEncryptToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memguard")
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret")
+
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+
+	if err := EncryptToFile(path, b, key); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.IsAlive() || !equalTo(b, []byte("yellow submarine")) {
+		t.Error("buffer was modified")
+	}
+
+	// Only ciphertext is written, and only the owner may access it.
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+	}
+	if bytes.Contains(data, []byte("yellow submarine")) {
+		t.Error("plaintext written to disk")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Error(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Error("unexpected permissions", info.Mode().Perm())
+	}
+
+	// Round trip.
+	o, err := DecryptFromFile(path, key)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(o, []byte("yellow submarine")) {
+		t.Error("unexpected plaintext; got", o.Bytes())
+	}
+	if o.IsMutable() {
+		t.Error("expected decrypted buffer to be immutable")
+	}
+	o.Destroy()
+
+	// Wrong key.
+	wrong := NewBufferRandom(32)
+	defer wrong.Destroy()
+	if o, err := DecryptFromFile(path, wrong); err != core.ErrDecryptionFailed || o.Size() != 0 {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+
+	// Existing files are replaced and no temporary files are left behind.
+	c := NewBufferFromBytes([]byte("another secret"))
+	if err := EncryptToFileAndDestroy(path, c, key); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if c.IsAlive() {
+		t.Error("expected buffer to be destroyed")
+	}
+	if o, err := DecryptFromFile(path, key); err != nil || !equalTo(o, []byte("another secret")) {
+		t.Error("unexpected result:", err)
+	}
+	if files, err := ioutil.ReadDir(dir); err != nil || len(files) != 1 {
+		t.Error("unexpected files in directory", files, err)
+	}
+
+	// Failures leave the destination and the plaintext untouched.
+	data, _ = ioutil.ReadFile(path)
+	short := NewBufferRandom(16)
+	defer short.Destroy()
+	if err := EncryptToFileAndDestroy(path, b, short); err != core.ErrInvalidKeyLength {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+	if !b.IsAlive() {
+		t.Error("buffer destroyed after failure")
+	}
+	if err := EncryptToFile(filepath.Join(dir, "missing", "secret"), b, key); err == nil {
+		t.Error("expected error writing to missing directory")
+	}
+	if after, _ := ioutil.ReadFile(path); !bytes.Equal(after, data) {
+		t.Error("destination changed by failed write")
+	}
+
+	// Malformed and missing files.
+	if err := ioutil.WriteFile(path, []byte{0, 0}, 0600); err != nil {
+		t.Error(err)
+	}
+	if _, err := DecryptFromFile(path, key); err != ErrInvalidSealedData {
+		t.Error("expected ErrInvalidSealedData; got", err)
+	}
+	if _, err := DecryptFromFile(filepath.Join(dir, "missing"), key); !os.IsNotExist(err) {
+		t.Error("expected not exist error; got", err)
+	}
+}
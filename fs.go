@@ -0,0 +1,153 @@
+// +build go1.16
+
+package memguard
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+AsFS returns a read-only filesystem holding a single file with the given name, whose contents are read directly from a LockedBuffer each time it is read. This allows a secret to be passed to an API that loads files through an fs.FS, such as a TLS key, without ever writing it to a real file. The file is the only entry of the root directory ".", so its name must be a single path element such as "key.pem"; for any other name the filesystem is empty.
+
+Reading the file copies data straight from guarded memory into the slice given to Read, relaxing the protection of an inaccessible LockedBuffer only for the duration of the copy. No intermediate copy is made, but the slices that the consumer reads into are its own and are not protected: the caller is responsible for wiping them, including any returned by fs.ReadFile.
+
+The LockedBuffer is not copied, so changes to it are visible through the filesystem and it must not be destroyed while the filesystem is in use. Operations on a destroyed LockedBuffer fail with core.ErrBufferExpired.
+*/
+func AsFS(name string, b *LockedBuffer) fs.FS {
+	if !fs.ValidPath(name) || name == "." || strings.Contains(name, "/") {
+		name = ""
+	}
+	return &bufferFS{name: name, b: b}
+}
+
+type bufferFS struct {
+	name string // Empty if the filesystem holds no file
+	b    *LockedBuffer
+}
+
+func (f *bufferFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	switch name {
+	case ".":
+		return &bufferDir{fs: f}, nil
+	case f.name:
+		if !f.b.IsAlive() {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: core.ErrBufferExpired}
+		}
+		return &bufferFile{fs: f}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// A file reading from the LockedBuffer of a bufferFS.
+type bufferFile struct {
+	fs     *bufferFS
+	offset int
+	closed bool
+}
+
+func (f *bufferFile) Stat() (fs.FileInfo, error) {
+	if f.closed {
+		return nil, &fs.PathError{Op: "stat", Path: f.fs.name, Err: fs.ErrClosed}
+	}
+	return bufferFileInfo{f.fs}, nil
+}
+
+func (f *bufferFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.fs.name, Err: fs.ErrClosed}
+	}
+	var n int
+	if err := f.fs.b.Access(false, func(data []byte) error {
+		if f.offset < len(data) {
+			n = copy(p, data[f.offset:])
+		}
+		return nil
+	}); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.fs.name, Err: err}
+	}
+	f.offset += n
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (f *bufferFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.fs.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+// The root directory of a bufferFS, listing its only file.
+type bufferDir struct {
+	fs     *bufferFS
+	listed bool
+	closed bool
+}
+
+func (d *bufferDir) Stat() (fs.FileInfo, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "stat", Path: ".", Err: fs.ErrClosed}
+	}
+	return bufferDirInfo{}, nil
+}
+
+func (d *bufferDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: ".", Err: fs.ErrInvalid}
+}
+
+func (d *bufferDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: ".", Err: fs.ErrClosed}
+	}
+	if d.listed || d.fs.name == "" {
+		if n > 0 {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}
+	d.listed = true
+	return []fs.DirEntry{bufferFileInfo{d.fs}}, nil
+}
+
+func (d *bufferDir) Close() error {
+	if d.closed {
+		return &fs.PathError{Op: "close", Path: ".", Err: fs.ErrClosed}
+	}
+	d.closed = true
+	return nil
+}
+
+// Describes the file of a bufferFS, both as a fs.FileInfo and as a fs.DirEntry.
+type bufferFileInfo struct {
+	fs *bufferFS
+}
+
+func (i bufferFileInfo) Name() string               { return i.fs.name }
+func (i bufferFileInfo) Size() int64                { return int64(i.fs.b.Size()) }
+func (i bufferFileInfo) Mode() fs.FileMode          { return 0400 }
+func (i bufferFileInfo) Type() fs.FileMode          { return 0 }
+func (i bufferFileInfo) ModTime() time.Time         { return time.Time{} }
+func (i bufferFileInfo) IsDir() bool                { return false }
+func (i bufferFileInfo) Sys() interface{}           { return nil }
+func (i bufferFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// Describes the root directory of a bufferFS.
+type bufferDirInfo struct{}
+
+func (bufferDirInfo) Name() string       { return "." }
+func (bufferDirInfo) Size() int64        { return 0 }
+func (bufferDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0500 }
+func (bufferDirInfo) ModTime() time.Time { return time.Time{} }
+func (bufferDirInfo) IsDir() bool        { return true }
+func (bufferDirInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,76 @@
+// +build go1.16
+
+package memguard
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestAsFS(t *testing.T) {
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+	fsys := AsFS("key.pem", b)
+
+	data, err := fs.ReadFile(fsys, "key.pem")
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if string(data) != "yellow submarine" {
+		t.Error("unexpected contents", data)
+	}
+	WipeBytes(data)
+
+	// The filesystem behaves like any other.
+	if err := fstest.TestFS(fsys, "key.pem"); err != nil {
+		t.Error(err)
+	}
+	info, err := fs.Stat(fsys, "key.pem")
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if info.Size() != 16 || info.Mode() != 0400 || info.Name() != "key.pem" {
+		t.Error("unexpected file info", info.Size(), info.Mode(), info.Name())
+	}
+
+	// Inaccessible buffers are read through their protection.
+	b.Protect(false, false)
+	if data, err := fs.ReadFile(fsys, "key.pem"); err != nil || string(data) != "yellow submarine" {
+		t.Error("unexpected result:", data, err)
+	}
+	if !faults(func() { faultSink = b.Bytes()[0] }) {
+		t.Error("protection was not restored")
+	}
+
+	// Other names do not exist, and invalid names leave the filesystem empty.
+	for _, name := range []string{"other", "key.pem/x", "../key.pem"} {
+		if _, err := fsys.Open(name); err == nil {
+			t.Error("expected error opening", name)
+		}
+	}
+	if _, err := fsys.Open("other"); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("expected ErrNotExist; got", err)
+	}
+	for _, name := range []string{"dir/key.pem", ".", ""} {
+		if entries, err := fs.ReadDir(AsFS(name, b), "."); err != nil || len(entries) != 0 {
+			t.Error("expected empty filesystem for", name, entries, err)
+		}
+	}
+
+	// Destroyed buffers.
+	f, err := fsys.Open("key.pem")
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	b.Destroy()
+	if _, err := f.Read(make([]byte, 16)); !errors.Is(err, core.ErrBufferExpired) {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if _, err := fsys.Open("key.pem"); !errors.Is(err, core.ErrBufferExpired) {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -5,5 +5,5 @@ go 1.14
 require (
 	github.com/awnumar/memcall v0.0.0-20191004114545-73db50fd9f80
 	golang.org/x/crypto v0.0.0-20200311171314-f7b00557c8c4
-	golang.org/x/sys v0.0.0-20200302150141-5c8b2ff67527 // indirect
+	golang.org/x/sys v0.0.0-20200302150141-5c8b2ff67527
 )
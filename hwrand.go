@@ -0,0 +1,83 @@
+package memguard
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrHardwareRandomFailed is returned when the CPU's random number generator repeatedly fails to return a value.
+var ErrHardwareRandomFailed = errors.New("<memguard::ErrHardwareRandomFailed> hardware random number generator did not return a value")
+
+// Reports whether the CPU has a random number generator, replaceable in tests.
+var hardwareRandom = hasRDSEED || hasRDRAND
+
+/*
+HardwareRandomAvailable reports whether NewFromHardwareRandom draws from the CPU's random number generator. It is true on amd64 processors that support the RDSEED or RDRAND instructions.
+*/
+func HardwareRandomAvailable() bool {
+	return hardwareRandom
+}
+
+/*
+NewFromHardwareRandom constructs an immutable LockedBuffer of a given length filled with random bytes from the CPU's random number generator, for environments that require a hardware entropy source. Values are written straight into guarded memory. RDSEED, which returns output of the entropy source itself, is preferred, with RDRAND, which returns output of a generator reseeded from it, used when RDSEED is unavailable or has been exhausted.
+
+If the CPU has no random number generator, the LockedBuffer is filled from core.RandReader instead, as by NewBufferRandom, and any error reading from it is returned. HardwareRandomAvailable reports which source is used.
+
+A length of less than one returns a null buffer and core.ErrNullBuffer. ErrHardwareRandomFailed is returned along with a null buffer if the generator keeps failing to return a value, which should only happen if it is faulty.
+*/
+func NewFromHardwareRandom(length int) (*LockedBuffer, error) {
+	if length < 1 {
+		return newNullBuffer(), core.ErrNullBuffer
+	}
+
+	fill := core.Scramble
+	if hardwareRandom {
+		fill = fillHardwareRandom
+	}
+
+	b := NewBuffer(length)
+	if err := b.Access(true, fill); err != nil {
+		b.Destroy()
+		return newNullBuffer(), err
+	}
+
+	b.Freeze()
+	return b, nil
+}
+
+// Fills a buffer with values from the CPU's random number generator.
+func fillHardwareRandom(data []byte) error {
+	var word [8]byte
+	defer core.Wipe(word[:])
+	for len(data) > 0 {
+		v, ok := hardwareRandom64()
+		if !ok {
+			return ErrHardwareRandomFailed
+		}
+		binary.LittleEndian.PutUint64(word[:], v)
+		n := copy(data, word[:])
+		data = data[n:]
+	}
+	return nil
+}
+
+// Reads a value from RDSEED, falling back to RDRAND, retrying each a bounded number of times as they may transiently have no value available.
+func hardwareRandom64() (uint64, bool) {
+	if hasRDSEED {
+		for i := 0; i < 16; i++ {
+			if v, ok := rdseed64(); ok {
+				return v, true
+			}
+		}
+	}
+	if hasRDRAND {
+		for i := 0; i < 10; i++ {
+			if v, ok := rdrand64(); ok {
+				return v, true
+			}
+		}
+	}
+	return 0, false
+}
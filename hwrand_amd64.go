@@ -0,0 +1,16 @@
+// +build amd64
+
+package memguard
+
+import "golang.org/x/sys/cpu"
+
+var (
+	hasRDRAND = cpu.X86.HasRDRAND
+	hasRDSEED = cpu.X86.HasRDSEED
+)
+
+// Returns a random value from the RDRAND instruction, and whether one was available.
+func rdrand64() (v uint64, ok bool)
+
+// Returns a random value from the RDSEED instruction, and whether one was available.
+func rdseed64() (v uint64, ok bool)
@@ -0,0 +1,17 @@
+// +build amd64
+
+#include "textflag.h"
+
+// func rdrand64() (uint64, bool)
+TEXT ·rdrand64(SB), NOSPLIT, $0-9
+	RDRANDQ AX
+	SETCS   ok+8(FP)
+	MOVQ    AX, v+0(FP)
+	RET
+
+// func rdseed64() (uint64, bool)
+TEXT ·rdseed64(SB), NOSPLIT, $0-9
+	RDSEEDQ AX
+	SETCS   ok+8(FP)
+	MOVQ    AX, v+0(FP)
+	RET
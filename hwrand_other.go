@@ -0,0 +1,12 @@
+// +build !amd64
+
+package memguard
+
+const (
+	hasRDRAND = false
+	hasRDSEED = false
+)
+
+func rdrand64() (uint64, bool) { return 0, false }
+
+func rdseed64() (uint64, bool) { return 0, false }
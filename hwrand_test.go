@@ -0,0 +1,55 @@
+package memguard
+
+import (
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestNewFromHardwareRandom(t *testing.T) {
+	check := func() {
+		a, err := NewFromHardwareRandom(4096)
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		defer a.Destroy()
+		if a.Size() != 4096 || a.IsMutable() {
+			t.Error("unexpected buffer:", a.Size(), a.IsMutable())
+		}
+		if equalTo(a, make([]byte, 4096)) {
+			t.Error("buffer was not filled")
+		}
+		if e, err := a.ShannonEntropy(); err != nil || e < 7.5 {
+			t.Error("unexpected entropy:", e, err)
+		}
+
+		b, err := NewFromHardwareRandom(4096)
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		defer b.Destroy()
+		if equalTo(b, a.Bytes()) {
+			t.Error("outputs are identical")
+		}
+
+		// Lengths that are not a multiple of eight are filled in full.
+		c, err := NewFromHardwareRandom(13)
+		if err != nil || c.Size() != 13 {
+			t.Error("unexpected result:", c.Size(), err)
+		}
+		c.Destroy()
+	}
+
+	if HardwareRandomAvailable() {
+		check()
+	}
+
+	// Without a hardware source, the buffer is filled from RandReader.
+	defer func(v bool) { hardwareRandom = v }(hardwareRandom)
+	hardwareRandom = false
+	check()
+
+	if b, err := NewFromHardwareRandom(0); err != core.ErrNullBuffer || b.Size() != 0 {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+}
@@ -0,0 +1,225 @@
+package memguard
+
+import (
+	"encoding/json"
+	"errors"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrInvalidJSON is returned when data that should hold a JSON object is malformed or holds some other value.
+var ErrInvalidJSON = errors.New("<memguard::ErrInvalidJSON> data is not a valid JSON object")
+
+// ErrJSONFieldNotFound is returned when a JSON object does not have a requested field.
+var ErrJSONFieldNotFound = errors.New("<memguard::ErrJSONFieldNotFound> field is not present in the JSON object")
+
+// ErrJSONFieldNotString is returned when a requested field of a JSON object does not hold a string.
+var ErrJSONFieldNotString = errors.New("<memguard::ErrJSONFieldNotString> field of the JSON object is not a string")
+
+/*
+NewFromJSONField extracts the string value of a field of a JSON object, such as the token in {"token":"..."}, into an immutable LockedBuffer, and then wipes the source data. Unmarshalling the object with encoding/json would leave the secret behind in strings that cannot be wiped; instead the document is scanned in place and only the value of the field is decoded, with its escape sequences, directly into guarded memory. Invalid UTF-8 and unpaired surrogates are replaced with U+FFFD, as by encoding/json.
+
+Only the members of the outermost object are considered, and as with encoding/json the last one wins if the field appears more than once. Field names are matched exactly. A null buffer is returned if the value is the empty string.
+
+ErrInvalidJSON is returned if the data is not a valid JSON object, ErrJSONFieldNotFound if the object has no such field, and ErrJSONFieldNotString if its value is not a string. The source data is wiped in every case.
+*/
+func NewFromJSONField(data []byte, field string) (*LockedBuffer, error) {
+	defer core.Wipe(data)
+
+	value, err := findJSONField(data, field)
+	if err != nil {
+		return newNullBuffer(), err
+	}
+	size := decodeJSONString(nil, value)
+	if size == 0 {
+		return newNullBuffer(), nil
+	}
+
+	b := NewBuffer(size)
+	if err := b.Access(true, func(buf []byte) error {
+		decodeJSONString(buf, value)
+		return nil
+	}); err != nil {
+		b.Destroy()
+		return newNullBuffer(), err
+	}
+
+	b.Freeze()
+	return b, nil
+}
+
+// Returns the raw contents, between the quotes, of the string value of the last member of a JSON object with a given name.
+func findJSONField(data []byte, field string) ([]byte, error) {
+	// Validation does not copy any of the data, and allows the scan to assume the document is well formed.
+	if !json.Valid(data) {
+		return nil, ErrInvalidJSON
+	}
+	i := skipJSONSpace(data, 0)
+	if data[i] != '{' {
+		return nil, ErrInvalidJSON
+	}
+
+	var value []byte
+	found, isString := false, false
+	for i = skipJSONSpace(data, i+1); data[i] != '}'; {
+		// Each member is a key, a colon and a value, followed by a comma or the closing brace.
+		end := skipJSONValue(data, i)
+		key := data[i+1 : end-1]
+		i = skipJSONSpace(data, skipJSONSpace(data, end)+1)
+		end = skipJSONValue(data, i)
+		if jsonKeyEquals(key, field) {
+			found, isString = true, data[i] == '"'
+			if isString {
+				value = data[i+1 : end-1]
+			}
+		}
+		if i = skipJSONSpace(data, end); data[i] == ',' {
+			i = skipJSONSpace(data, i+1)
+		}
+	}
+
+	if !found {
+		return nil, ErrJSONFieldNotFound
+	}
+	if !isString {
+		return nil, ErrJSONFieldNotString
+	}
+	return value, nil
+}
+
+// Reports whether the raw contents of a JSON string decode to a given name.
+func jsonKeyEquals(raw []byte, name string) bool {
+	if decodeJSONString(nil, raw) != len(name) {
+		return false
+	}
+	key := make([]byte, len(name))
+	decodeJSONString(key, raw)
+	return string(key) == name
+}
+
+// Returns the index of the first byte at or after i in valid JSON that is not whitespace.
+func skipJSONSpace(data []byte, i int) int {
+	for i < len(data) && (data[i] == ' ' || data[i] == '\t' || data[i] == '\n' || data[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// Returns the index just after the value starting at i in valid JSON.
+func skipJSONValue(data []byte, i int) int {
+	switch data[i] {
+	case '"':
+		for i++; data[i] != '"'; i++ {
+			if data[i] == '\\' {
+				i++
+			}
+		}
+		return i + 1
+	case '{', '[':
+		depth := 0
+		for {
+			switch data[i] {
+			case '"':
+				i = skipJSONValue(data, i)
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+	default:
+		// Numbers and literals run until the next delimiter.
+		for i < len(data) && data[i] != ',' && data[i] != '}' && data[i] != ']' && skipJSONSpace(data, i) == i {
+			i++
+		}
+		return i
+	}
+}
+
+// Decodes the raw contents of a valid JSON string into dst, returning the length of the result. If dst is nil, only the length is computed.
+func decodeJSONString(dst, src []byte) int {
+	var r [utf8.UTFMax]byte
+	defer core.Wipe(r[:])
+
+	n := 0
+	for i := 0; i < len(src); {
+		var w int
+		switch c := src[i]; {
+		case c == '\\' && src[i+1] == 'u':
+			x := parseJSONHex(src[i+2:])
+			i += 6
+			if utf16.IsSurrogate(x) {
+				// A valid pair is decoded together; anything else is replaced.
+				y := unicode.ReplacementChar
+				if i+6 <= len(src) && src[i] == '\\' && src[i+1] == 'u' {
+					y = utf16.DecodeRune(x, parseJSONHex(src[i+2:]))
+				}
+				if y != unicode.ReplacementChar {
+					i += 6
+				}
+				x = y
+			}
+			w = utf8.EncodeRune(r[:], x)
+		case c == '\\':
+			r[0], w = unescapeJSON(src[i+1]), 1
+			i += 2
+		case c < utf8.RuneSelf:
+			r[0], w = c, 1
+			i++
+		default:
+			x, size := utf8.DecodeRune(src[i:])
+			if x == utf8.RuneError && size == 1 {
+				w = utf8.EncodeRune(r[:], x)
+			} else {
+				w = copy(r[:], src[i:i+size])
+			}
+			i += size
+		}
+		if dst != nil {
+			copy(dst[n:], r[:w])
+		}
+		n += w
+	}
+	return n
+}
+
+// Parses the four hexadecimal digits of a JSON \u escape.
+func parseJSONHex(s []byte) rune {
+	var x rune
+	for _, c := range s[:4] {
+		switch {
+		case c >= 'a':
+			c -= 'a' - 10
+		case c >= 'A':
+			c -= 'A' - 10
+		default:
+			c -= '0'
+		}
+		x = x<<4 | rune(c)
+	}
+	return x
+}
+
+// Returns the byte represented by a single-character JSON escape.
+func unescapeJSON(c byte) byte {
+	switch c {
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	}
+	return c
+}
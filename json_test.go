@@ -0,0 +1,79 @@
+package memguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestNewFromJSONField(t *testing.T) {
+	data := []byte(`{"user": "bob", "token": "s3cr3t", "n": 1}`)
+	b, err := NewFromJSONField(data, "token")
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("s3cr3t")) || b.IsMutable() {
+		t.Error("unexpected result", b.String())
+	}
+	b.Destroy()
+	if !bytes.Equal(data, make([]byte, len(data))) {
+		t.Error("source was not wiped")
+	}
+
+	// Values and keys are decoded exactly as by encoding/json.
+	for _, doc := range []string{
+		`{"token":"a\"b\\c\/d\b\f\n\r\t"}`,
+		`{"token":"café é 😀"}`,
+		`{"token":"\u00e9\ud83d\ude00\ud800x\udc00\ud800A\ud800"}`,
+		"{\"token\":\"caf\xc3\xa9 \xff\xfe\"}",
+		`{"to\u006ben":"escaped key"}`,
+		` { "a" : [1, "}", {"token": "inner"}] , "token" : "outer" , "b" : true } `,
+		`{"token":"first","token":"last"}`,
+		`{"token":{"token":"no"},"token":"yes"}`,
+	} {
+		var expected map[string]interface{}
+		if err := json.Unmarshal([]byte(doc), &expected); err != nil {
+			t.Fatal(err)
+		}
+		b, err := NewFromJSONField([]byte(doc), "token")
+		if err != nil {
+			t.Error("unexpected error for", doc, err)
+		}
+		if b.String() != expected["token"] {
+			t.Errorf("decoded %q from %s; expected %q", b.String(), doc, expected["token"])
+		}
+		b.Destroy()
+	}
+
+	// An empty value gives a null buffer.
+	if b, err := NewFromJSONField([]byte(`{"token":""}`), "token"); err != nil || b.Size() != 0 {
+		t.Error("expected null buffer; got", b.Size(), err)
+	}
+
+	for _, c := range []struct {
+		doc string
+		err error
+	}{
+		{`{"user":"bob"}`, ErrJSONFieldNotFound},
+		{`{}`, ErrJSONFieldNotFound},
+		{`{"a":{"token":"nested"}}`, ErrJSONFieldNotFound},
+		{`{"Token":"x"}`, ErrJSONFieldNotFound},
+		{`{"token":123}`, ErrJSONFieldNotString},
+		{`{"token":null}`, ErrJSONFieldNotString},
+		{`{"token":["x"]}`, ErrJSONFieldNotString},
+		{`{"token":"x","token":false}`, ErrJSONFieldNotString},
+		{`{"token":"x"`, ErrInvalidJSON},
+		{`["token","x"]`, ErrInvalidJSON},
+		{`"token"`, ErrInvalidJSON},
+		{``, ErrInvalidJSON},
+	} {
+		data := []byte(c.doc)
+		b, err := NewFromJSONField(data, "token")
+		if err != c.err || b.Size() != 0 {
+			t.Error("expected", c.err, "for", c.doc, "got", err)
+		}
+		if !bytes.Equal(data, make([]byte, len(data))) {
+			t.Error("source was not wiped for", c.doc)
+		}
+	}
+}
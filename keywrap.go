@@ -0,0 +1,151 @@
+package memguard
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrInvalidKEKLength is returned when attempting to wrap or unwrap a key with a key-encryption key that is not 16, 24, or 32 bytes in size.
+var ErrInvalidKEKLength = errors.New("<memguard::ErrInvalidKEKLength> key-encryption key must be 16, 24, or 32 bytes")
+
+// ErrInvalidWrapLength is returned when the key to be wrapped is not a multiple of 8 bytes of at least 16 bytes, or when the wrapped key is not a multiple of 8 bytes of at least 24 bytes.
+var ErrInvalidWrapLength = errors.New("<memguard::ErrInvalidWrapLength> key data must be a multiple of 8 bytes and at least 16 bytes")
+
+// ErrUnwrapFailed is returned when the integrity check of a wrapped key fails. This can occur if the key-encryption key is incorrect or if the wrapped key has been modified.
+var ErrUnwrapFailed = errors.New("<memguard::ErrUnwrapFailed> key unwrapping failed")
+
+// Default initial value defined in RFC 3394 section 2.2.3.1.
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+/*
+WrapKey wraps a key under a key-encryption key using the AES Key Wrap algorithm defined in RFC 3394. The wrapped key is returned inside an immutable LockedBuffer and is 8 bytes longer than the key.
+
+The key-encryption key must be 16, 24, or 32 bytes long, and the key must be a multiple of 8 bytes and at least 16 bytes long. All intermediate values are computed inside guarded memory, although the AES key schedule derived from the key-encryption key is held by the standard library.
+*/
+func (kek *LockedBuffer) WrapKey(key *LockedBuffer) (*LockedBuffer, error) {
+	if !kek.IsAlive() || !key.IsAlive() {
+		return newNullBuffer(), core.ErrBufferExpired
+	}
+	if !validKEKLength(kek.Size()) {
+		return newNullBuffer(), ErrInvalidKEKLength
+	}
+	if key.Size() < 16 || key.Size()%8 != 0 {
+		return newNullBuffer(), ErrInvalidWrapLength
+	}
+
+	// The output holds A || R[1] || ... || R[n].
+	out := NewBuffer(key.Size() + 8)
+	core.Copy(out.Bytes()[:8], keyWrapIV)
+	if err := key.Access(false, func(data []byte) error {
+		core.Copy(out.Bytes()[8:], data)
+		return nil
+	}); err != nil {
+		out.Destroy()
+		return newNullBuffer(), err
+	}
+
+	if err := kek.keyWrap(out.Bytes(), true); err != nil {
+		out.Destroy()
+		return newNullBuffer(), err
+	}
+
+	out.Freeze()
+	return out, nil
+}
+
+/*
+UnwrapKey unwraps a key that was wrapped under a key-encryption key using the AES Key Wrap algorithm defined in RFC 3394. The unwrapped key is returned inside an immutable LockedBuffer.
+
+The integrity check value is verified in constant time and ErrUnwrapFailed is returned if it does not match, in which case no key material is returned.
+*/
+func (kek *LockedBuffer) UnwrapKey(wrapped []byte) (*LockedBuffer, error) {
+	if !kek.IsAlive() {
+		return newNullBuffer(), core.ErrBufferExpired
+	}
+	if !validKEKLength(kek.Size()) {
+		return newNullBuffer(), ErrInvalidKEKLength
+	}
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return newNullBuffer(), ErrInvalidWrapLength
+	}
+
+	// Unwrap into guarded memory.
+	work := NewBuffer(len(wrapped))
+	defer work.Destroy()
+	work.Copy(wrapped)
+	if err := kek.keyWrap(work.Bytes(), false); err != nil {
+		return newNullBuffer(), err
+	}
+
+	// Verify the integrity check value.
+	if !core.Equal(work.Bytes()[:8], keyWrapIV) {
+		return newNullBuffer(), ErrUnwrapFailed
+	}
+
+	out := NewBuffer(len(wrapped) - 8)
+	out.Copy(work.Bytes()[8:])
+	out.Freeze()
+	return out, nil
+}
+
+// Performs the wrapping or unwrapping rounds in place on A || R[1] || ... || R[n].
+func (kek *LockedBuffer) keyWrap(data []byte, wrap bool) error {
+	var block cipher.Block
+	if err := kek.Access(false, func(key []byte) (err error) {
+		block, err = aes.NewCipher(key)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// Guarded scratch block holding B.
+	b := NewBuffer(16)
+	defer b.Destroy()
+	scratch := b.Bytes()
+
+	a := data[:8]
+	n := (len(data) / 8) - 1
+	var t [8]byte
+
+	for s := 0; s < 6; s++ {
+		for k := 1; k <= n; k++ {
+			// Iterate forwards to wrap and backwards to unwrap.
+			j, i := s, k
+			if !wrap {
+				j, i = 5-s, n+1-k
+			}
+			r := data[8*i : 8*(i+1)]
+			binary.BigEndian.PutUint64(t[:], uint64((n*j)+i))
+
+			if wrap {
+				// B = AES(K, A | R[i]); A = MSB(64, B) ^ t; R[i] = LSB(64, B)
+				copy(scratch[:8], a)
+				copy(scratch[8:], r)
+				block.Encrypt(scratch, scratch)
+				for x := range a {
+					a[x] = scratch[x] ^ t[x]
+				}
+				copy(r, scratch[8:])
+			} else {
+				// B = AES-1(K, (A ^ t) | R[i]); A = MSB(64, B); R[i] = LSB(64, B)
+				for x := range a {
+					scratch[x] = a[x] ^ t[x]
+				}
+				copy(scratch[8:], r)
+				block.Decrypt(scratch, scratch)
+				copy(a, scratch[:8])
+				copy(r, scratch[8:])
+			}
+		}
+	}
+
+	return nil
+}
+
+func validKEKLength(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
@@ -0,0 +1,127 @@
+package memguard
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestWrapKey(t *testing.T) {
+	// RFC 3394 section 4 test vectors.
+	known := []struct {
+		kek, key, wrapped string
+	}{
+		{"000102030405060708090A0B0C0D0E0F", "00112233445566778899AABBCCDDEEFF", "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"},
+		{"000102030405060708090A0B0C0D0E0F1011121314151617", "00112233445566778899AABBCCDDEEFF", "96778B25AE6CA435F92B5B97C050AED2468AB8A17AD84E5D"},
+		{"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF", "64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7"},
+		{"000102030405060708090A0B0C0D0E0F1011121314151617", "00112233445566778899AABBCCDDEEFF0001020304050607", "031D33264E15D33268F24EC260743EDCE1C6C7DDEE725A936BA814915C6762D2"},
+		{"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF0001020304050607", "A8F9BC1612C68B3FF6E6F4FBE30E71E4769C8B80A32CB8958CD5D17D6B254DA1"},
+		{"000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F", "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21"},
+	}
+
+	for _, v := range known {
+		kekBytes, _ := hex.DecodeString(v.kek)
+		keyBytes, _ := hex.DecodeString(v.key)
+		expected, _ := hex.DecodeString(v.wrapped)
+		plain := make([]byte, len(keyBytes))
+		copy(plain, keyBytes)
+
+		kek := NewBufferFromBytes(kekBytes)
+		key := NewBufferFromBytes(keyBytes)
+
+		wrapped, err := kek.WrapKey(key)
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(wrapped.Bytes(), expected) {
+			t.Error("wrapped key does not match known value", hex.EncodeToString(wrapped.Bytes()))
+		}
+		if wrapped.IsMutable() {
+			t.Error("wrapped key should be immutable")
+		}
+
+		unwrapped, err := kek.UnwrapKey(wrapped.Bytes())
+		if err != nil {
+			t.Error(err)
+		}
+		if !bytes.Equal(unwrapped.Bytes(), plain) {
+			t.Error("unwrapped key does not match")
+		}
+		if unwrapped.IsMutable() {
+			t.Error("unwrapped key should be immutable")
+		}
+
+		kek.Destroy()
+		key.Destroy()
+		wrapped.Destroy()
+		unwrapped.Destroy()
+	}
+}
+
+func TestUnwrapKey(t *testing.T) {
+	kek := NewBufferRandom(32)
+	key := NewBufferRandom(32)
+	defer kek.Destroy()
+	defer key.Destroy()
+
+	wrapped, err := kek.WrapKey(key)
+	if err != nil {
+		t.Error(err)
+	}
+	data := make([]byte, wrapped.Size())
+	copy(data, wrapped.Bytes())
+	wrapped.Destroy()
+
+	// Tamper with every byte in turn.
+	for i := range data {
+		data[i] ^= 0x01
+		b, err := kek.UnwrapKey(data)
+		if err != ErrUnwrapFailed {
+			t.Error("expected ErrUnwrapFailed; got", err)
+		}
+		if b.IsAlive() {
+			t.Error("expected null buffer")
+		}
+		data[i] ^= 0x01
+	}
+
+	// Wrong key-encryption key.
+	other := NewBufferRandom(32)
+	if _, err := other.UnwrapKey(data); err != ErrUnwrapFailed {
+		t.Error("expected ErrUnwrapFailed; got", err)
+	}
+	other.Destroy()
+
+	// Invalid lengths.
+	short := NewBufferRandom(20)
+	if _, err := short.WrapKey(key); err != ErrInvalidKEKLength {
+		t.Error("expected ErrInvalidKEKLength; got", err)
+	}
+	if _, err := short.UnwrapKey(data); err != ErrInvalidKEKLength {
+		t.Error("expected ErrInvalidKEKLength; got", err)
+	}
+	short.Destroy()
+	odd := NewBufferRandom(12)
+	if _, err := kek.WrapKey(odd); err != ErrInvalidWrapLength {
+		t.Error("expected ErrInvalidWrapLength; got", err)
+	}
+	odd.Destroy()
+	if _, err := kek.UnwrapKey(data[:16]); err != ErrInvalidWrapLength {
+		t.Error("expected ErrInvalidWrapLength; got", err)
+	}
+	if _, err := kek.UnwrapKey(data[:len(data)-1]); err != ErrInvalidWrapLength {
+		t.Error("expected ErrInvalidWrapLength; got", err)
+	}
+
+	// Destroyed buffers.
+	if _, err := kek.WrapKey(odd); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	dead := NewBufferRandom(32)
+	dead.Destroy()
+	if _, err := dead.UnwrapKey(data); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,19 @@
+package memguard
+
+import (
+	"bytes"
+)
+
+/*
+ScanForLeaks reports whether the contents of a LockedBuffer appear anywhere within a given region of memory, such as a core dump or heap profile that has been read from disk. It is a diagnostic tool for verifying that secrets are not escaping guarded memory, and should not be used in production code paths.
+
+The search is not done in constant time. The LockedBuffer is made readable for the duration of the search if it has been made inaccessible. An empty LockedBuffer is never found. core.ErrBufferExpired is returned if it has been destroyed.
+*/
+func ScanForLeaks(haystack []byte, needle *LockedBuffer) (bool, error) {
+	var found bool
+	err := needle.Access(false, func(secret []byte) error {
+		found = len(secret) > 0 && bytes.Contains(haystack, secret)
+		return nil
+	})
+	return found, err
+}
@@ -0,0 +1,40 @@
+package memguard
+
+import (
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestScanForLeaks(t *testing.T) {
+	secret := NewBufferRandom(32)
+	secret.Protect(false, false)
+
+	// Plant the secret in the middle of a haystack.
+	haystack := make([]byte, 4096)
+	ScrambleBytes(haystack)
+	secret.CopyTo(haystack[1000:])
+	found, err := ScanForLeaks(haystack, secret)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !found {
+		t.Error("planted secret not found")
+	}
+
+	// A partial copy should not be detected.
+	haystack[1031] ^= 0xff
+	found, err = ScanForLeaks(haystack, secret)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if found {
+		t.Error("secret found in haystack not containing it")
+	}
+
+	// A destroyed needle cannot be searched for.
+	secret.Destroy()
+	if _, err := ScanForLeaks(haystack, secret); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,55 @@
+package memguard
+
+import (
+	"crypto/sha256"
+	"errors"
+	"hash"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrMACMismatch is returned when a message authentication code does not match the expected value.
+var ErrMACMismatch = errors.New("<memguard::ErrMACMismatch> message authentication code does not match")
+
+// ErrInvalidLength is returned when two values that must be the same length, such as a message authentication code and its expected value, differ in length.
+var ErrInvalidLength = errors.New("<memguard::ErrInvalidLength> values differ in length")
+
+/*
+VerifyMAC compares a message authentication code held in a LockedBuffer against an expected value in constant time. It returns nil if they are equal and ErrMACMismatch if they are not.
+
+Since the length of a MAC is fixed by the algorithm that produced it, a difference in length always indicates a programming error and so ErrInvalidLength is returned instead. The length of a MAC is not secret, so this check is not done in constant time. core.ErrBufferExpired is returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) VerifyMAC(expected []byte) error {
+	return b.Access(false, func(mac []byte) error {
+		if len(mac) != len(expected) {
+			return ErrInvalidLength
+		}
+		if !core.Equal(mac, expected) {
+			return ErrMACMismatch
+		}
+		return nil
+	})
+}
+
+/*
+HMAC computes an HMAC over a message using a key held in a LockedBuffer, and returns the tag inside a new immutable LockedBuffer. The hash function is given by h, which defaults to SHA-256 if it is nil.
+
+The key is used directly from guarded memory and the intermediate state of the computation is kept inside guarded memory and wiped afterwards, so neither the key nor the tag touch the unguarded heap. core.ErrBufferExpired is returned if the key has been destroyed.
+*/
+func (key *LockedBuffer) HMAC(message []byte, h func() hash.Hash) (*LockedBuffer, error) {
+	if h == nil {
+		h = sha256.New
+	}
+
+	tag := NewBuffer(h().Size())
+	if err := key.Access(false, func(k []byte) error {
+		_, err := core.HMAC(h, k, message, tag.Bytes())
+		return err
+	}); err != nil {
+		tag.Destroy()
+		return newNullBuffer(), err
+	}
+
+	tag.Freeze()
+	return tag, nil
+}
@@ -0,0 +1,93 @@
+package memguard
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestVerifyMAC(t *testing.T) {
+	h := hmac.New(sha256.New, []byte("yellow submarine"))
+	h.Write([]byte("attack at dawn"))
+	expected := h.Sum(nil)
+
+	b := NewBufferFromBytes(h.Sum(nil))
+	if err := b.VerifyMAC(expected); err != nil {
+		t.Error("expected match; got", err)
+	}
+
+	// Change a single bit.
+	expected[len(expected)-1] ^= 1
+	if err := b.VerifyMAC(expected); err != ErrMACMismatch {
+		t.Error("expected ErrMACMismatch; got", err)
+	}
+
+	// Differing lengths.
+	if err := b.VerifyMAC(expected[:len(expected)-1]); err != ErrInvalidLength {
+		t.Error("expected ErrInvalidLength; got", err)
+	}
+	if err := b.VerifyMAC(append(expected, 0)); err != ErrInvalidLength {
+		t.Error("expected ErrInvalidLength; got", err)
+	}
+	if err := b.VerifyMAC(nil); err != ErrInvalidLength {
+		t.Error("expected ErrInvalidLength; got", err)
+	}
+
+	b.Destroy()
+	if err := b.VerifyMAC(expected); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	// RFC 4231 test cases, excluding the one with a truncated output.
+	largeKey := bytes.Repeat([]byte{0xaa}, 131)
+	known := []struct {
+		key, message []byte
+		h            func() hash.Hash
+		tag          string
+	}{
+		{bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"), nil,
+			"b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7"},
+		{[]byte("Jefe"), []byte("what do ya want for nothing?"), sha256.New,
+			"5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{bytes.Repeat([]byte{0xaa}, 20), bytes.Repeat([]byte{0xdd}, 50), sha256.New,
+			"773ea91e36800e46854db8ebd09181a72959098b3ef8c122d9635514ced565fe"},
+		{[]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25}, bytes.Repeat([]byte{0xcd}, 50), sha256.New,
+			"82558a389a443c0ea4cc819899f2083a85f0faa3e578f8077a2e3ff46729665b"},
+		{largeKey, []byte("Test Using Larger Than Block-Size Key - Hash Key First"), sha256.New,
+			"60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54"},
+		{largeKey, []byte("This is a test using a larger than block-size key and a larger than block-size data. The key needs to be hashed before being used by the HMAC algorithm."), sha256.New,
+			"9b09ffa71b942fcb27635fbcd5b0e944bfdc63644f0713938a7f51535c3a35e2"},
+		{bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"), sha512.New,
+			"87aa7cdea5ef619d4ff0b4241a1d6cb02379f4e2ce4ec2787ad0b30545e17cdedaa833b7d6b8a702038b274eaea3f4e4be9d914eeb61f1702e696c203a126854"},
+	}
+
+	for i, v := range known {
+		key := NewBufferFromBytes(append([]byte(nil), v.key...))
+		key.Protect(false, false)
+		tag, err := key.HMAC(v.message, v.h)
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		expected, _ := hex.DecodeString(v.tag)
+		if !equalTo(tag, expected) {
+			t.Error("tag doesn't match known value; case", i, "got", hex.EncodeToString(tag.Bytes()))
+		}
+		if !tag.IsAlive() || tag.IsMutable() {
+			t.Error("tag should be an immutable guarded buffer")
+		}
+		tag.Destroy()
+		key.Destroy()
+
+		if _, err := key.HMAC(v.message, v.h); err != core.ErrBufferExpired {
+			t.Error("expected ErrBufferExpired; got", err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package memguard
 
 import (
+	"flag"
+	"sync/atomic"
+
 	"github.com/awnumar/memguard/core"
 )
 
@@ -23,12 +26,113 @@ func WipeBytes(buf []byte) {
 }
 
 /*
-Purge resets the session key to a fresh value and destroys all existing LockedBuffers. Existing Enclave objects will no longer be decryptable.
+Purge resets the session key to a fresh value and destroys all existing LockedBuffers. Existing Enclave objects will no longer be decryptable. The registry of named LockedBuffers is also cleared.
 */
 func Purge() {
+	clearRegistry()
 	core.Purge()
 }
 
+/*
+DestroyAllExcept destroys every existing LockedBuffer other than those given, which are left untouched, and removes the destroyed ones from the registry of named LockedBuffers. Unlike Purge, the session key is kept, so existing Enclave objects remain decryptable. This is useful when rekeying, to wipe every old key while keeping the new ones.
+*/
+func DestroyAllExcept(keep ...*LockedBuffer) {
+	buffers := make([]*core.Buffer, 0, len(keep))
+	for _, b := range keep {
+		if b != nil && b.Buffer != nil {
+			buffers = append(buffers, b.Buffer)
+		}
+	}
+	core.DestroyAllExcept(buffers...)
+	pruneRegistry()
+}
+
+/*
+PeakLockedBytes returns the highest total number of bytes of memory that have been locked at any one time over the lifetime of the process. Every LockedBuffer occupies a whole number of pages, and the memory used internally to protect Enclave objects is included. The value can be used to set an appropriate memory locking limit (RLIMIT_MEMLOCK on Unix systems) or to detect unexpected spikes in usage.
+*/
+func PeakLockedBytes() int {
+	return core.PeakLockedBytes()
+}
+
+/*
+Stats holds counters describing the LockedBuffers that have been destroyed over the lifetime of the process: how many there have been, and the total time spent wiping and freeing their memory. The memory used internally to protect Enclave objects is included.
+*/
+type Stats = core.Stats
+
+/*
+GetStats returns a snapshot of the counters kept about destroyed LockedBuffers, whether they were destroyed by Destroy, Purge, DestroyAllExcept or SafeExit. The counters are maintained atomically, so it is safe to call at any time. This helps to diagnose shutdown latency when many large LockedBuffers are involved.
+*/
+func GetStats() Stats {
+	return core.GetStats()
+}
+
+/*
+Logger receives messages about events that do not cause an operation to fail but that may still be of interest, such as a protection that could not be applied. Messages never include the contents of any LockedBuffer.
+*/
+type Logger = core.Logger
+
+/*
+SetLogger sets the Logger that receives messages from memguard. By default, and if l is nil, messages are discarded.
+*/
+func SetLogger(l Logger) {
+	core.SetLogger(l)
+}
+
+/*
+SetVerifyCanaryOnAccess enables or disables a paranoid mode in which every operation that reads or modifies a LockedBuffer first checks that the canary in front of its data is intact. Memory corruption can then be detected at the next use of a LockedBuffer rather than only when it is destroyed, at the cost of hashing the canary on each access. If corruption is detected, everything is wiped as if by SafePanic.
+
+It is disabled by default.
+*/
+func SetVerifyCanaryOnAccess(enabled bool) {
+	core.SetVerifyCanaryOnAccess(enabled)
+}
+
+/*
+SetVerifyLockOnCreate enables or disables checking that the memory of every new LockedBuffer is resident immediately after it has been locked. This detects systems, such as misconfigured containers, where locking memory silently has no effect and so secrets could be swapped out to disk. If the check fails, the memory is freed and creating the LockedBuffer fails with core.ErrLockIneffective; constructors that cannot return an error, such as NewBuffer, return a null buffer.
+
+It is disabled by default, and can only detect the problem on Linux.
+*/
+func SetVerifyLockOnCreate(enabled bool) {
+	core.SetVerifyLockOnCreate(enabled)
+}
+
+// Set to 1 if offsets given to methods that cannot return an error should be validated, and to 0 if not. Until it is set, it is -1 and checking is enabled only in test binaries.
+var boundsChecking int32 = -1
+
+/*
+SetBoundsChecking enables or disables the validation of offsets given to CopyAt and MoveAt, which cannot report an error. While enabled, an offset outside of a LockedBuffer's data leaves it unchanged; while disabled, such an offset causes a runtime panic, as slicing the data would. No method can write outside of a LockedBuffer's data into its canary or guard pages either way. To be told about a bad offset, use CopyAtChecked and MoveAtChecked, which always validate their offsets and return core.ErrOutOfBounds, as do the other methods that return an error.
+
+By default it is enabled while running tests, so that bad offsets are caught there, and disabled otherwise. A test binary is recognised by the flags that the testing package registers.
+*/
+func SetBoundsChecking(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&boundsChecking, v)
+}
+
+// Reports whether an offset lies within some data, if bounds checking is enabled or always is true.
+func checkOffset(data []byte, offset int, always bool) bool {
+	if !always && !boundsCheckingEnabled() {
+		return true
+	}
+	return offset >= 0 && offset <= len(data)
+}
+
+// Reports whether bounds checking is enabled, settling the default on first use. The testing package registers its flags before any test runs, but after package initialisation.
+func boundsCheckingEnabled() bool {
+	if v := atomic.LoadInt32(&boundsChecking); v >= 0 {
+		return v == 1
+	}
+	var v int32
+	if flag.Lookup("test.v") != nil {
+		v = 1
+	}
+	atomic.CompareAndSwapInt32(&boundsChecking, -1, v)
+	return atomic.LoadInt32(&boundsChecking) == 1
+}
+
 /*
 SafePanic wipes all it can before calling panic(v).
 */
@@ -36,9 +140,36 @@ func SafePanic(v interface{}) {
 	core.Panic(v)
 }
 
+/*
+GuardPanics wipes all it can if the calling goroutine is panicking, before allowing the panic to continue. It complements CatchInterrupt by making sure that an unexpected runtime error does not leave sensitive data behind for a crash dump.
+
+It must be deferred directly, ideally as the first statement of your main function:
+
+	func main() {
+		defer memguard.GuardPanics()
+		...
+	}
+
+Only panics on the goroutine that defers GuardPanics are caught. The original panic value is re-raised once the session has been purged.
+*/
+func GuardPanics() {
+	if v := recover(); v != nil {
+		core.Panic(v)
+	}
+}
+
 /*
 SafeExit destroys everything sensitive before exiting with a specified status code.
+
+Calling os.Exit directly skips deferred functions and finalizers, so any LockedBuffer that has not been destroyed explicitly is left in memory, where it may end up in a core dump. Programs should therefore call SafeExit wherever they would otherwise call os.Exit, including from the handler passed to CatchSignal.
 */
 func SafeExit(c int) {
 	core.Exit(c)
 }
+
+/*
+Exit destroys every LockedBuffer, along with the key that protects Enclave objects, and then calls os.Exit with the given status code. It is a drop-in replacement for os.Exit and behaves exactly like SafeExit.
+*/
+func Exit(code int) {
+	SafeExit(code)
+}
@@ -0,0 +1,53 @@
+// +build linux
+
+package memguard
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSafeExit(t *testing.T) {
+	// If we're within the testing subprocess, put the secret in a buffer of our own and exit.
+	if os.Getenv("WITHIN_SUBPROCESS") == "1" {
+		shared, err := AttachShared(3, 16)
+		if err != nil {
+			SafePanic(err)
+		}
+		b := NewBufferFromBytes([]byte("yellow submarine"))
+		shared.Copy(b.Bytes())
+		SafeExit(0)
+	}
+
+	// Share some memory with the subprocess so that we can see it being wiped.
+	shared, fd, err := NewShared(16)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	defer shared.Destroy()
+	dup, err := unix.Dup(int(fd))
+	if err != nil {
+		t.Error(err)
+	}
+	f := os.NewFile(uintptr(dup), "shared")
+	defer f.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestSafeExit$")
+	cmd.Env = append(os.Environ(), "WITHIN_SUBPROCESS=1")
+	cmd.ExtraFiles = []*os.File{f}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Error("subprocess failed:", err, string(out))
+	}
+	if string(out) != "" {
+		t.Error("subprocess did not exit before completing the test:", string(out))
+	}
+
+	// The shared view was destroyed by SafeExit, wiping the data.
+	if !equalTo(shared, make([]byte, 16)) {
+		t.Error("buffers were not destroyed before exiting; got", shared.Bytes())
+	}
+}
@@ -2,6 +2,11 @@ package memguard
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/awnumar/memguard/core"
@@ -42,3 +47,249 @@ func TestPurge(t *testing.T) {
 		t.Error("buffer not nil:", buf)
 	}
 }
+
+func TestDestroyAllExcept(t *testing.T) {
+	key := NewEnclaveRandom(32)
+	var all []*LockedBuffer
+	for i := 0; i < 4; i++ {
+		all = append(all, NewBufferRandom(32))
+	}
+	Register("kept", all[0])
+	Register("dropped", all[1])
+	defer Unregister("kept")
+
+	DestroyAllExcept(all[0], all[2], nil)
+
+	for i, b := range all {
+		if b.IsAlive() != (i == 0 || i == 2) {
+			t.Error("unexpected state for buffer", i)
+		}
+	}
+	if _, ok := Lookup("kept"); !ok {
+		t.Error("kept buffer removed from registry")
+	}
+	if _, ok := Lookup("dropped"); ok {
+		t.Error("destroyed buffer left in registry")
+	}
+
+	// Enclaves remain decryptable.
+	buf, err := key.Open()
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	buf.Destroy()
+	all[2].Destroy()
+}
+
+func TestPeakLockedBytes(t *testing.T) {
+	// Allocate a burst of buffers of four pages each.
+	burst := make([]*LockedBuffer, 64)
+	for i := range burst {
+		burst[i] = NewBuffer(4 * os.Getpagesize())
+	}
+	peak := PeakLockedBytes()
+	if peak < len(burst)*4*os.Getpagesize() {
+		t.Error("peak does not reflect burst; got", peak)
+	}
+
+	// The peak should be unaffected by freeing the buffers.
+	for _, b := range burst {
+		b.Destroy()
+	}
+	if PeakLockedBytes() != peak {
+		t.Error("peak changed after freeing; got", PeakLockedBytes(), "expected", peak)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	before := GetStats()
+
+	// Destroy a large buffer.
+	b := NewBuffer(1 << 22)
+	b.Scramble()
+	b.Destroy()
+
+	after := GetStats()
+	if after.Destroyed != before.Destroyed+1 {
+		t.Error("destroy not counted; got", after.Destroyed, "expected", before.Destroyed+1)
+	}
+	if after.DestroyTime <= before.DestroyTime {
+		t.Error("destroy time did not increase; got", after.DestroyTime, "before", before.DestroyTime)
+	}
+
+	// Destroying it again is not counted.
+	b.Destroy()
+	if GetStats() != after {
+		t.Error("repeated destroy was counted")
+	}
+}
+
+type captureLogger struct {
+	warnings []string
+}
+
+func (l *captureLogger) Warnf(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func (l *captureLogger) Infof(string, ...interface{}) {}
+
+func TestSetLogger(t *testing.T) {
+	l := new(captureLogger)
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	// Simulate a degraded allocation by binding to a node that cannot exist.
+	b := NewBufferOnNode(32, -1)
+	if !b.IsAlive() {
+		t.Error("expected fallback allocation")
+	}
+	b.Destroy()
+	if len(l.warnings) != 1 {
+		t.Error("expected a warning; got", l.warnings)
+	}
+}
+
+func TestSetVerifyCanaryOnAccess(t *testing.T) {
+	defer SetVerifyCanaryOnAccess(false)
+	SetVerifyCanaryOnAccess(true)
+
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	if !equalTo(b, []byte("yellow submarine")) {
+		t.Error("unexpected value")
+	}
+
+	// Corrupt the byte just before the data.
+	inner := b.Inner()
+	b.Melt()
+	inner[len(inner)-b.Size()-1] ^= 0xff
+
+	panicked := func() (panicked bool) {
+		defer func() {
+			panicked = recover() != nil
+		}()
+		equalTo(b, []byte("yellow submarine"))
+		return
+	}()
+	if !panicked {
+		t.Error("expected corruption to be detected")
+	}
+	if !bytes.Equal(b.Bytes(), make([]byte, 16)) {
+		t.Error("buffers were not wiped")
+	}
+
+	// Repair the canary so that the buffer can be destroyed.
+	inner[len(inner)-b.Size()-1] ^= 0xff
+	b.Destroy()
+}
+
+func TestSetBoundsChecking(t *testing.T) {
+	// It is enabled by default in tests.
+	defer atomic.StoreInt32(&boundsChecking, atomic.LoadInt32(&boundsChecking))
+	atomic.StoreInt32(&boundsChecking, -1)
+	if !boundsCheckingEnabled() {
+		t.Error("expected bounds checking to be enabled in tests")
+	}
+
+	b := NewBuffer(32)
+	defer b.Destroy()
+	b.Copy([]byte("yellow submarine"))
+	value := append([]byte("yellow submarine"), make([]byte, 16)...)
+
+	// Out of range offsets leave the buffer unchanged.
+	for _, offset := range []int{-1, 33, 1 << 20} {
+		b.CopyAt(offset, []byte("purple"))
+		src := []byte("purple")
+		b.MoveAt(offset, src)
+		if !equalTo(b, value) {
+			t.Error("buffer modified for offset", offset)
+		}
+		if !bytes.Equal(src, make([]byte, 6)) {
+			t.Error("source not wiped for offset", offset)
+		}
+	}
+
+	// The end of the buffer is in range.
+	b.CopyAt(32, []byte("purple"))
+	b.CopyAt(26, []byte("purple"))
+	if !equalTo(b, append(value[:26], "purple"...)) {
+		t.Error("copy at end failed; got", b.Bytes())
+	}
+
+	// Without checking, bad offsets panic as slicing would.
+	SetBoundsChecking(false)
+	defer SetBoundsChecking(true)
+	panicked := func() (p bool) {
+		defer func() {
+			p = recover() != nil
+		}()
+		b.CopyAt(33, []byte("purple"))
+		return false
+	}()
+	if !panicked {
+		t.Error("expected panic with bounds checking disabled")
+	}
+	b.CopyAt(0, []byte("purple"))
+	if !equalTo(b, append(append([]byte("purple"), value[6:26]...), "purple"...)) {
+		t.Error("buffer not writable after panic; got", b.Bytes())
+	}
+}
+
+func TestGuardPanics(t *testing.T) {
+	// If we're within the testing subprocess, run test.
+	if os.Getenv("WITHIN_SUBPROCESS") == "1" {
+		b := NewBufferRandom(32)
+		defer func() {
+			// Runs after GuardPanics has handled the panic.
+			if !b.IsAlive() && b.Bytes() == nil {
+				os.Stdout.WriteString("buffers wiped\n")
+			}
+		}()
+		defer GuardPanics()
+		panic("unexpected")
+	}
+
+	// Execute the subprocess and inspect its output.
+	cmd := exec.Command(os.Args[0], "-test.run=TestGuardPanics")
+	cmd.Env = append(os.Environ(), "WITHIN_SUBPROCESS=1")
+	out, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Error("expected subprocess to panic; got", err)
+	}
+	if !strings.Contains(string(out), "buffers wiped") {
+		t.Error("buffers were not wiped before panicking:", string(out))
+	}
+	if !strings.Contains(string(out), "unexpected") {
+		t.Error("original panic value was not re-raised:", string(out))
+	}
+
+	// Does nothing when not panicking.
+	func() {
+		defer GuardPanics()
+	}()
+}
+
+func TestExit(t *testing.T) {
+	// If we're within the testing subprocess, run test.
+	if os.Getenv("WITHIN_SUBPROCESS") == "1" {
+		b := NewBufferRandom(32)
+		b.OnDestroy(func() {
+			// Runs when Exit destroys the buffer, before the process terminates.
+			if !b.IsAlive() && b.Bytes() == nil {
+				os.Stdout.WriteString("buffers wiped\n")
+			}
+		})
+		Exit(3)
+	}
+
+	// Execute the subprocess and inspect its exit code and output.
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExit$")
+	cmd.Env = append(os.Environ(), "WITHIN_SUBPROCESS=1")
+	out, err := cmd.CombinedOutput()
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 3 {
+		t.Error("expected exit code 3; got", err)
+	}
+	if !strings.Contains(string(out), "buffers wiped") {
+		t.Error("buffers were not wiped before exiting:", string(out))
+	}
+}
@@ -0,0 +1,85 @@
+package memguard
+
+import (
+	"time"
+
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+Option configures the construction of a LockedBuffer by NewWithOptions. Options are applied in order, so a later option overrides an earlier one of the same kind.
+*/
+type Option func(*options)
+
+type options struct {
+	core    core.BufferOptions
+	pattern []byte
+}
+
+/*
+WithoutLock leaves the memory of a LockedBuffer unlocked, so that it may be swapped to disk. It is still surrounded by guard pages and a canary. This is only intended for environments in which memory cannot be locked at all, such as some containers, and should not otherwise be used.
+*/
+func WithoutLock() Option {
+	return func(o *options) {
+		o.core.NoLock = true
+	}
+}
+
+/*
+WithInitPattern fills the data of a LockedBuffer with a repeating pattern instead of zeroes. The pattern is truncated if it is longer than the LockedBuffer, and an empty pattern has no effect. The pattern is copied, so it may be modified or wiped afterwards.
+*/
+func WithInitPattern(pattern []byte) Option {
+	pattern = append([]byte(nil), pattern...)
+	return func(o *options) {
+		o.pattern = pattern
+	}
+}
+
+/*
+WithLockTimeout gives up with core.ErrLockTimeout if locking the memory of a LockedBuffer does not complete within a given duration. See NewBufferWithTimeout.
+*/
+func WithLockTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.core.Timeout = d
+	}
+}
+
+/*
+WithNode binds the memory of a LockedBuffer to a given NUMA node. See NewBufferOnNode.
+*/
+func WithNode(node int) Option {
+	return func(o *options) {
+		o.core.Bind = true
+		o.core.Node = node
+	}
+}
+
+/*
+NewWithOptions creates a mutable data container of the specified size, configured by any number of options. With no options it is equivalent to NewBuffer, except that an error is returned rather than a null buffer if the memory could not be allocated. A size of less than one returns a null buffer.
+
+	b, err := memguard.NewWithOptions(32, memguard.WithLockTimeout(time.Second), memguard.WithInitPattern([]byte{0xdb}))
+*/
+func NewWithOptions(size int, opts ...Option) (*LockedBuffer, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	buf, err := core.NewBufferWithOptions(size, o.core)
+	if err != nil {
+		if err == core.ErrNullBuffer {
+			return newNullBuffer(), nil
+		}
+		return newNullBuffer(), err
+	}
+
+	// Fill the data with the pattern.
+	if len(o.pattern) > 0 {
+		data := buf.Data()
+		for i := 0; i < len(data); i += len(o.pattern) {
+			core.Copy(data[i:], o.pattern)
+		}
+	}
+
+	return newBuffer(buf), nil
+}
@@ -0,0 +1,60 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	// No options.
+	b, err := NewWithOptions(32)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !b.IsAlive() || !b.IsMutable() || !bytes.Equal(b.Bytes(), make([]byte, 32)) {
+		t.Error("invalid buffer")
+	}
+	b.Destroy()
+
+	// Combined options.
+	b, err = NewWithOptions(7, WithoutLock(), WithInitPattern([]byte("abc")), WithLockTimeout(time.Second))
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("abcabca")) {
+		t.Error("unexpected value", b.String())
+	}
+	b.Destroy()
+
+	// Later options override earlier ones.
+	pattern := []byte{0xdb}
+	opt := WithInitPattern(pattern)
+	pattern[0] = 0 // the pattern is copied
+	b, err = NewWithOptions(4, WithInitPattern([]byte("x")), opt, WithNode(-1))
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte{0xdb, 0xdb, 0xdb, 0xdb}) {
+		t.Error("unexpected value", b.Bytes())
+	}
+	b.Destroy()
+
+	// Long and empty patterns.
+	b, _ = NewWithOptions(4, WithInitPattern([]byte("yellow submarine")))
+	if !equalTo(b, []byte("yell")) {
+		t.Error("unexpected value", b.String())
+	}
+	b.Destroy()
+	b, _ = NewWithOptions(4, WithInitPattern(nil))
+	if !equalTo(b, make([]byte, 4)) {
+		t.Error("unexpected value", b.Bytes())
+	}
+	b.Destroy()
+
+	// Null buffers.
+	b, err = NewWithOptions(0, WithoutLock())
+	if err != nil || b.IsAlive() {
+		t.Error("expected null buffer; got", err)
+	}
+}
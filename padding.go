@@ -0,0 +1,41 @@
+package memguard
+
+import (
+	"errors"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrInvalidBlockSize is returned when a padding block size is outside of the range [1, 255].
+var ErrInvalidBlockSize = errors.New("<memguard::ErrInvalidBlockSize> block size must be between 1 and 255")
+
+/*
+PadPKCS7 returns a new LockedBuffer holding the data of a LockedBuffer followed by PKCS #7 padding, as defined in RFC 5652 section 6.3, so that its size is a multiple of blockSize. A full block of padding is added if the size is already a multiple of blockSize. The padded data is assembled directly inside guarded memory and the original is left unchanged.
+
+The new LockedBuffer is immutable if the original is. ErrInvalidBlockSize is returned if blockSize is not between 1 and 255, and core.ErrBufferExpired is returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) PadPKCS7(blockSize int) (*LockedBuffer, error) {
+	if blockSize < 1 || blockSize > 255 {
+		return newNullBuffer(), ErrInvalidBlockSize
+	}
+	mutable := b.IsMutable()
+
+	c := newNullBuffer()
+	if err := b.Access(false, func(data []byte) error {
+		n := blockSize - (len(data) % blockSize)
+		c = NewBuffer(len(data) + n)
+		out := c.Bytes()
+		core.Copy(out, data)
+		for i := len(data); i < len(out); i++ {
+			out[i] = byte(n)
+		}
+		return nil
+	}); err != nil {
+		return c, err
+	}
+
+	if !mutable {
+		c.Freeze()
+	}
+	return c, nil
+}
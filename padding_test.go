@@ -0,0 +1,69 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestPadPKCS7(t *testing.T) {
+	cases := []struct {
+		data      string
+		blockSize int
+		expected  []byte
+	}{
+		{"yellow", 8, append([]byte("yellow"), 2, 2)},
+		{"yellow s", 8, append([]byte("yellow s"), 8, 8, 8, 8, 8, 8, 8, 8)},
+		{"yellow submarine", 16, append([]byte("yellow submarine"), bytes.Repeat([]byte{16}, 16)...)},
+		{"yellow submarine", 20, append([]byte("yellow submarine"), 4, 4, 4, 4)},
+		{"abc", 1, append([]byte("abc"), 1)},
+		{"abc", 255, append([]byte("abc"), bytes.Repeat([]byte{252}, 252)...)},
+	}
+
+	for _, c := range cases {
+		b := NewBuffer(len(c.data))
+		b.Copy([]byte(c.data))
+		p, err := b.PadPKCS7(c.blockSize)
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if !bytes.Equal(p.Bytes(), c.expected) {
+			t.Error("unexpected padding; got", p.Bytes(), "expected", c.expected)
+		}
+		if p.Size()%c.blockSize != 0 {
+			t.Error("padded size", p.Size(), "not a multiple of", c.blockSize)
+		}
+		if !b.IsAlive() || string(b.Bytes()) != c.data {
+			t.Error("original modified")
+		}
+		if !p.IsMutable() {
+			t.Error("expected padded buffer to be mutable")
+		}
+		b.Destroy()
+		p.Destroy()
+	}
+
+	// Immutability is preserved.
+	b := NewBufferFromBytes([]byte("yellow"))
+	p, err := b.PadPKCS7(8)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if p.IsMutable() {
+		t.Error("expected padded buffer to be immutable")
+	}
+	p.Destroy()
+
+	// Invalid block sizes.
+	for _, size := range []int{-1, 0, 256} {
+		if p, err := b.PadPKCS7(size); err != ErrInvalidBlockSize || p.IsAlive() {
+			t.Error("expected ErrInvalidBlockSize; got", err)
+		}
+	}
+
+	b.Destroy()
+	if p, err := b.PadPKCS7(8); err != core.ErrBufferExpired || p.IsAlive() {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
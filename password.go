@@ -0,0 +1,111 @@
+package memguard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrInvalidPasswordHash is returned when a stored password hash is not in the encoded Argon2 format or exceeds the accepted cost.
+var ErrInvalidPasswordHash = errors.New("<memguard::ErrInvalidPasswordHash> password hash is malformed, unsupported or too costly")
+
+/*
+Argon2Params holds the cost parameters of the Argon2 password hashing function: the number of passes over the memory, the amount of memory in KiB, the number of threads, and the length of the derived key in bytes.
+*/
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	KeyLen  uint32
+}
+
+/*
+DeriveKey derives a key from a password held in a LockedBuffer using Argon2id with the given salt and parameters, and returns it inside a new immutable LockedBuffer. The password is read directly from guarded memory and the derived key is moved into guarded memory, wiping the copy returned by the golang.org/x/crypto package. The memory that Argon2 uses internally while hashing is allocated by that package and is not wiped.
+
+A KeyLen of zero returns a null buffer and core.ErrNullBuffer. An error is also returned if the password has been destroyed.
+*/
+func DeriveKey(password *LockedBuffer, salt []byte, params Argon2Params) (*LockedBuffer, error) {
+	return deriveArgon2(argon2.IDKey, password, salt, params)
+}
+
+/*
+VerifyPassword reports whether a candidate password held in a LockedBuffer matches a stored hash in the standard encoded Argon2 format, such as
+
+	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+
+where the salt and hash are encoded in unpadded base64. Both the argon2id and argon2i variants of version 19 are supported. The candidate is hashed with the salt and parameters embedded in the stored hash, the result is kept in guarded memory and compared to the stored hash in constant time, and it is destroyed afterwards.
+
+The parameters embedded in the stored hash are only accepted if none of them exceeds the corresponding field of params, which bounds the cost of a verification even if the stored hash has been tampered with. ErrInvalidPasswordHash is returned if they do, or if the stored hash cannot be parsed. An error is also returned if the candidate has been destroyed.
+*/
+func VerifyPassword(candidate *LockedBuffer, storedHash []byte, params Argon2Params) (bool, error) {
+	kdf, salt, hash, p, ok := decodeArgon2Hash(string(storedHash))
+	if !ok || p.Time > params.Time || p.Memory > params.Memory || p.Threads > params.Threads || p.KeyLen > params.KeyLen {
+		return false, ErrInvalidPasswordHash
+	}
+
+	derived, err := deriveArgon2(kdf, candidate, salt, p)
+	if err != nil {
+		return false, err
+	}
+	defer derived.Destroy()
+	return derived.EqualTo(hash)
+}
+
+// Derives a key from a password with a given variant of Argon2.
+func deriveArgon2(kdf func(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte, password *LockedBuffer, salt []byte, params Argon2Params) (*LockedBuffer, error) {
+	if params.KeyLen == 0 {
+		return newNullBuffer(), core.ErrNullBuffer
+	}
+
+	var key []byte
+	if err := password.Access(false, func(data []byte) error {
+		key = kdf(data, salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+		return nil
+	}); err != nil {
+		return newNullBuffer(), err
+	}
+
+	b := NewBuffer(len(key))
+	b.Move(key)
+	b.Freeze()
+	return b, nil
+}
+
+// Parses an encoded Argon2 hash, returning the function computing its variant along with its salt, hash and parameters.
+func decodeArgon2Hash(encoded string) (kdf func(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte, salt, hash []byte, params Argon2Params, ok bool) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) != 6 || fields[0] != "" || fields[2] != "v=19" {
+		return nil, nil, nil, params, false
+	}
+	switch fields[1] {
+	case "argon2id":
+		kdf = argon2.IDKey
+	case "argon2i":
+		kdf = argon2.Key
+	default:
+		return nil, nil, nil, params, false
+	}
+
+	var extra string
+	if n, _ := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d%s", &params.Memory, &params.Time, &params.Threads, &extra); n != 3 {
+		return nil, nil, nil, params, false
+	}
+	if params.Time == 0 || params.Threads == 0 {
+		return nil, nil, nil, params, false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return nil, nil, nil, params, false
+	}
+	hash, err = base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil || len(hash) == 0 {
+		return nil, nil, nil, params, false
+	}
+	params.KeyLen = uint32(len(hash))
+	return kdf, salt, hash, params, true
+}
@@ -0,0 +1,88 @@
+package memguard
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/argon2"
+)
+
+// Output of the reference implementation for the password "password" and the salt "somesalt".
+const referenceHash = "$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+
+func TestVerifyPassword(t *testing.T) {
+	limits := Argon2Params{Time: 3, Memory: 65536, Threads: 4, KeyLen: 32}
+	right := NewBufferFromBytes([]byte("password"))
+	defer right.Destroy()
+	wrong := NewBufferFromBytes([]byte("passwore"))
+	defer wrong.Destroy()
+
+	if ok, err := VerifyPassword(right, []byte(referenceHash), limits); err != nil || !ok {
+		t.Error("expected match; got", ok, err)
+	}
+	if ok, err := VerifyPassword(wrong, []byte(referenceHash), limits); err != nil || ok {
+		t.Error("expected mismatch; got", ok, err)
+	}
+	if !equalTo(right, []byte("password")) {
+		t.Error("candidate was modified")
+	}
+
+	// Hashes produced by DeriveKey verify.
+	params := Argon2Params{Time: 1, Memory: 256, Threads: 2, KeyLen: 16}
+	key, err := DeriveKey(right, []byte("somesalt"), params)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(key, argon2.IDKey([]byte("password"), []byte("somesalt"), 1, 256, 2, 16)) || key.IsMutable() {
+		t.Error("unexpected derived key")
+	}
+	stored := []byte("$argon2id$v=19$m=256,t=1,p=2$c29tZXNhbHQ$" + base64.RawStdEncoding.EncodeToString(key.Bytes()))
+	key.Destroy()
+	if ok, err := VerifyPassword(right, stored, limits); err != nil || !ok {
+		t.Error("expected match; got", ok, err)
+	}
+	if ok, err := VerifyPassword(wrong, stored, limits); err != nil || ok {
+		t.Error("expected mismatch; got", ok, err)
+	}
+
+	// Hashes exceeding the limits are refused.
+	for _, l := range []Argon2Params{
+		{Time: 1, Memory: 65536, Threads: 4, KeyLen: 32},
+		{Time: 3, Memory: 1024, Threads: 4, KeyLen: 32},
+		{Time: 3, Memory: 65536, Threads: 1, KeyLen: 32},
+		{Time: 3, Memory: 65536, Threads: 4, KeyLen: 8},
+	} {
+		if _, err := VerifyPassword(right, []byte(referenceHash), l); err != ErrInvalidPasswordHash {
+			t.Error("expected ErrInvalidPasswordHash; got", err, l)
+		}
+	}
+
+	// Malformed hashes.
+	for _, h := range []string{
+		"",
+		"$argon2d$v=19$m=256,t=1,p=2$c29tZXNhbHQ$AAAA",
+		"$argon2id$v=16$m=256,t=1,p=2$c29tZXNhbHQ$AAAA",
+		"$argon2id$m=256,t=1,p=2$c29tZXNhbHQ$AAAA",
+		"$argon2id$v=19$m=256,t=1$c29tZXNhbHQ$AAAA",
+		"$argon2id$v=19$m=256,t=1,p=2,x=1$c29tZXNhbHQ$AAAA",
+		"$argon2id$v=19$m=256,t=0,p=2$c29tZXNhbHQ$AAAA",
+		"$argon2id$v=19$m=256,t=1,p=300$c29tZXNhbHQ$AAAA",
+		"$argon2id$v=19$m=256,t=1,p=2$c29tZXNhbHQ=$AAAA",
+		"$argon2id$v=19$m=256,t=1,p=2$c29tZXNhbHQ$",
+		"$argon2id$v=19$m=256,t=1,p=2$c29tZXNhbHQ$AAAA$",
+	} {
+		if _, err := VerifyPassword(right, []byte(h), limits); err != ErrInvalidPasswordHash {
+			t.Error("expected ErrInvalidPasswordHash for", h, "; got", err)
+		}
+	}
+
+	// Invalid arguments.
+	if b, err := DeriveKey(right, nil, Argon2Params{Time: 1, Memory: 64, Threads: 1}); err != core.ErrNullBuffer || b.IsAlive() {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+	wrong.Destroy()
+	if _, err := VerifyPassword(wrong, stored, limits); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,27 @@
+package memguard
+
+import (
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+PKCS11Session is the part of a PKCS#11 binding needed to import key material from a token. It is satisfied by a small adapter around whichever binding the caller already uses, so that memguard does not depend on one itself.
+*/
+type PKCS11Session interface {
+	// ObjectValue returns the value (CKA_VALUE) of the object with the given handle. The token's policy must allow the object to be extracted.
+	ObjectValue(handle uint) ([]byte, error)
+}
+
+/*
+NewBufferFromPKCS11 reads the value of an object held by a PKCS#11 token into an immutable LockedBuffer, and wipes the slice returned by the session.
+
+The binding necessarily returns the value in ordinary memory, so it briefly exists outside of guarded memory until it is moved and wiped. Any copies made within the binding itself are outside of memguard's control. If the session returns an error, any value it returned is wiped and a null buffer is returned along with the error.
+*/
+func NewBufferFromPKCS11(session PKCS11Session, handle uint) (*LockedBuffer, error) {
+	value, err := session.ObjectValue(handle)
+	if err != nil {
+		core.Wipe(value)
+		return newNullBuffer(), err
+	}
+	return NewBufferFromBytes(value), nil
+}
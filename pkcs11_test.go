@@ -0,0 +1,57 @@
+package memguard
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type mockPKCS11Session struct {
+	objects map[uint][]byte
+	err     error
+}
+
+func (s *mockPKCS11Session) ObjectValue(handle uint) ([]byte, error) {
+	value, ok := s.objects[handle]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return value, s.err
+}
+
+func TestNewBufferFromPKCS11(t *testing.T) {
+	value := []byte("yellow submarine")
+	session := &mockPKCS11Session{objects: map[uint][]byte{7: value}}
+
+	b, err := NewBufferFromPKCS11(session, 7)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("yellow submarine")) {
+		t.Error("incorrect data; got", b.Bytes())
+	}
+	if b.IsMutable() {
+		t.Error("expected buffer to be immutable")
+	}
+	if !bytes.Equal(value, make([]byte, 16)) {
+		t.Error("source not wiped")
+	}
+	b.Destroy()
+
+	// Errors are forwarded.
+	b, err = NewBufferFromPKCS11(session, 8)
+	if err == nil || b.IsAlive() {
+		t.Error("expected error and null buffer; got", err)
+	}
+
+	// Values returned alongside an error are wiped.
+	value = []byte("yellow submarine")
+	session = &mockPKCS11Session{objects: map[uint][]byte{7: value}, err: errors.New("failed")}
+	b, err = NewBufferFromPKCS11(session, 7)
+	if err != session.err || b.IsAlive() {
+		t.Error("expected error and null buffer; got", err)
+	}
+	if !bytes.Equal(value, make([]byte, 16)) {
+		t.Error("source not wiped")
+	}
+}
@@ -0,0 +1,155 @@
+package memguard
+
+import (
+	"os"
+	"sort"
+	"sync"
+)
+
+/*
+Pool keeps LockedBuffers that are no longer needed so that they can be reused, sparing workloads that repeatedly need short-lived secrets the cost of allocating, locking and freeing memory every time. Idle buffers stay allocated and locked until they are reused or the Pool is destroyed. Buffers are grouped into size classes: each class holds buffers with a capacity of a power of two number of bytes, and no less than a page, and a request is served from the smallest class that fits it.
+
+Every buffer handed out by Get is wiped and sized to the request. A Pool is safe for concurrent use and should be destroyed with Destroy once it is no longer needed.
+*/
+type Pool struct {
+	sync.Mutex
+	max     int
+	classes map[int]*poolClass
+}
+
+// The idle buffers and statistics of a single size class.
+type poolClass struct {
+	idle  []*LockedBuffer
+	stats PoolStats
+}
+
+// PoolStats holds the statistics of a single size class of a Pool.
+type PoolStats struct {
+	Size      int    // Capacity of the buffers in this class
+	Idle      int    // Number of buffers currently waiting to be reused
+	Hits      uint64 // Number of calls to Get served by an idle buffer
+	Misses    uint64 // Number of calls to Get that allocated a new buffer
+	Evictions uint64 // Number of buffers destroyed by Put because the class was full
+}
+
+/*
+NewPool creates an empty Pool that keeps at most a given number of idle buffers in each size class. Buffers returned to a class that is full are destroyed.
+*/
+func NewPool(maxIdle int) *Pool {
+	return &Pool{max: maxIdle, classes: make(map[int]*poolClass)}
+}
+
+/*
+Get returns a mutable, wiped LockedBuffer of a given size, reusing an idle buffer from the smallest size class that fits if one is available and allocating a new one otherwise. The size of a LockedBuffer is its requested size, regardless of the capacity of its class. A size of less than one returns a null buffer.
+*/
+func (p *Pool) Get(size int) *LockedBuffer {
+	if size < 1 {
+		return newNullBuffer()
+	}
+	class := sizeClass(size)
+
+	p.Lock()
+	c := p.class(class)
+	var b *LockedBuffer
+	if n := len(c.idle); n != 0 {
+		b = c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	p.Unlock()
+
+	if b == nil {
+		b = NewBuffer(class)
+	}
+	if err := b.Buffer.Resize(size); err != nil {
+		b.Destroy()
+		return NewBuffer(size)
+	}
+	b.ClearDirty()
+	return b
+}
+
+/*
+Put returns a LockedBuffer obtained from Get to the Pool so that it can be reused. The buffer is wiped straight away, and is made readable and writable again if its protection had been changed. The caller must not use it afterwards.
+
+Buffers that have been destroyed are ignored. Buffers that did not come from a Pool, that have been frozen with FreezeImmutable, or that belong to a size class which is already full are destroyed instead.
+*/
+func (p *Pool) Put(b *LockedBuffer) {
+	if !b.IsAlive() {
+		return
+	}
+
+	// Wipe it and restore the default protection.
+	class := b.Buffer.Capacity()
+	if sizeClass(class) != class || b.SetAutoProtect(false) != nil || b.Protect(true, true) != nil {
+		b.Destroy()
+		return
+	}
+	b.Wipe()
+
+	p.Lock()
+	defer p.Unlock()
+
+	c := p.class(class)
+	if len(c.idle) >= p.max {
+		c.stats.Evictions++
+		b.Destroy()
+		return
+	}
+	c.idle = append(c.idle, b)
+}
+
+/*
+Stats returns the statistics of every size class that has been used, in increasing order of size.
+*/
+func (p *Pool) Stats() []PoolStats {
+	p.Lock()
+	defer p.Unlock()
+
+	stats := make([]PoolStats, 0, len(p.classes))
+	for _, c := range p.classes {
+		s := c.stats
+		s.Idle = len(c.idle)
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Size < stats[j].Size
+	})
+	return stats
+}
+
+/*
+Destroy destroys every idle buffer held by a Pool. Buffers that are in use are unaffected, and the Pool remains usable.
+*/
+func (p *Pool) Destroy() {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, c := range p.classes {
+		for _, b := range c.idle {
+			b.Destroy()
+		}
+		c.idle = nil
+	}
+}
+
+// Returns the statistics and idle buffers of a size class, creating it if needed. Must be called with the pool locked.
+func (p *Pool) class(size int) *poolClass {
+	c, ok := p.classes[size]
+	if !ok {
+		c = &poolClass{stats: PoolStats{Size: size}}
+		p.classes[size] = c
+	}
+	return c
+}
+
+// Returns the capacity of the smallest size class that can hold a given size: the next power of two, and at least a page.
+func sizeClass(size int) int {
+	class := os.Getpagesize()
+	for class < size {
+		class <<= 1
+	}
+	return class
+}
@@ -0,0 +1,129 @@
+package memguard
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSizeClass(t *testing.T) {
+	page := os.Getpagesize()
+	cases := []struct {
+		size, class int
+	}{
+		{1, page},
+		{page, page},
+		{page + 1, 2 * page},
+		{3 * page, 4 * page},
+		{4 * page, 4 * page},
+	}
+	for _, c := range cases {
+		if class := sizeClass(c.size); class != c.class {
+			t.Error("unexpected class for", c.size, "; got", class)
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	page := os.Getpagesize()
+	p := NewPool(1)
+	defer p.Destroy()
+
+	// Misses allocate buffers of the class capacity, sized to the request.
+	a := p.Get(32)
+	if a.Size() != 32 || a.Buffer.Capacity() != page || !a.IsMutable() {
+		t.Error("unexpected buffer", a.Size(), a.Buffer.Capacity())
+	}
+	b := p.Get(3 * page)
+	if b.Size() != 3*page || b.Buffer.Capacity() != 4*page {
+		t.Error("unexpected buffer", b.Size(), b.Buffer.Capacity())
+	}
+	a.Copy([]byte("yellow submarine"))
+	b.Copy([]byte("yellow submarine"))
+
+	// Returned buffers are reused from the smallest class that fits, wiped and resized.
+	p.Put(a)
+	p.Put(b)
+	c := p.Get(100)
+	if c.Buffer != a.Buffer {
+		t.Error("idle buffer not reused")
+	}
+	if c.Size() != 100 || !equalTo(c, make([]byte, 100)) {
+		t.Error("reused buffer not wiped and resized")
+	}
+	if c.IsDirty() {
+		t.Error("reused buffer should be clean")
+	}
+	d := p.Get(2*page + 1)
+	if d.Buffer != b.Buffer || d.Size() != 2*page+1 || !equalTo(d, make([]byte, 2*page+1)) {
+		t.Error("idle buffer not reused")
+	}
+
+	// Returning more buffers than a class holds evicts them.
+	e := p.Get(page)
+	p.Put(c)
+	p.Put(e)
+	if e.IsAlive() {
+		t.Error("evicted buffer not destroyed")
+	}
+
+	// Protection is reset, and permanently frozen or foreign buffers are destroyed.
+	d.SetAutoProtect(true)
+	d.Freeze()
+	p.Put(d)
+	f := p.Get(4 * page)
+	if f.Buffer != d.Buffer || !f.IsMutable() {
+		t.Error("protection not reset")
+	}
+	f.Bytes()[0] = 1 // test accessibility
+	f.FreezeImmutable()
+	p.Put(f)
+	if f.IsAlive() {
+		t.Error("permanently frozen buffer not destroyed")
+	}
+	g := NewBuffer(3 * page)
+	p.Put(g)
+	if g.IsAlive() {
+		t.Error("foreign buffer not destroyed")
+	}
+	p.Put(g)
+	p.Put(nil)
+
+	stats := p.Stats()
+	expected := []PoolStats{
+		{Size: page, Idle: 1, Hits: 1, Misses: 2, Evictions: 1},
+		{Size: 4 * page, Idle: 0, Hits: 2, Misses: 1, Evictions: 0},
+	}
+	if len(stats) != len(expected) {
+		t.Error("unexpected stats", stats)
+	} else {
+		for i := range stats {
+			if stats[i] != expected[i] {
+				t.Error("unexpected stats; got", stats[i], "expected", expected[i])
+			}
+		}
+	}
+
+	// Destroying the pool destroys idle buffers.
+	p.Destroy()
+	if c.IsAlive() {
+		t.Error("idle buffer not destroyed")
+	}
+	if p.Stats()[0].Idle != 0 {
+		t.Error("idle buffers remain")
+	}
+	if b := p.Get(0); b.IsAlive() {
+		t.Error("expected null buffer")
+	}
+}
+
+func BenchmarkPool(b *testing.B) {
+	p := NewPool(16)
+	defer p.Destroy()
+	sizes := []int{16, 32, 100, 4096, 5000, 12000, 64, 30000}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := p.Get(sizes[i%len(sizes)])
+		p.Put(buf)
+	}
+}
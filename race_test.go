@@ -0,0 +1,6 @@
+// +build race
+
+package memguard
+
+// Reports whether the race detector is enabled, which distorts timing measurements.
+const raceEnabled = true
@@ -0,0 +1,59 @@
+package memguard
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// The text that replaces each occurrence of a secret in a redacted error message.
+const redacted = "[REDACTED]"
+
+// An error whose message has had secrets removed from it.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+/*
+RedactError wraps an error so that every occurrence of the contents of the given LockedBuffers in its message is replaced with "[REDACTED]". This is a best-effort measure to keep secrets that have found their way into error messages, such as "failed to parse key XYZ", out of logs. The message of the original error is still ordinary memory and may already have been copied elsewhere, so secrets should not be included in errors in the first place.
+
+The message is redacted once, when RedactError is called, so the LockedBuffers may be destroyed afterwards. Longer secrets are replaced before shorter ones, and destroyed or empty LockedBuffers are ignored. The original error is available through errors.Unwrap, and so errors.Is and errors.As continue to work. A nil error is returned unchanged.
+*/
+func RedactError(err error, secrets ...*LockedBuffer) error {
+	if err == nil {
+		return nil
+	}
+
+	// Look for longer secrets first so that no part of one containing another survives.
+	secrets = append([]*LockedBuffer(nil), secrets...)
+	sort.SliceStable(secrets, func(i, j int) bool {
+		return secrets[i].Size() > secrets[j].Size()
+	})
+
+	msg := []byte(err.Error())
+	for _, s := range secrets {
+		s.Access(false, func(data []byte) error {
+			if len(data) == 0 || !bytes.Contains(msg, data) {
+				return nil
+			}
+			r := bytes.ReplaceAll(msg, data, []byte(redacted))
+			core.Wipe(msg)
+			msg = r
+			return nil
+		})
+	}
+
+	e := &redactedError{err: err, msg: string(msg)}
+	core.Wipe(msg)
+	return e
+}
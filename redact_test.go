@@ -0,0 +1,52 @@
+package memguard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedactError(t *testing.T) {
+	key := NewBufferFromBytes([]byte("hunter2"))
+	defer key.Destroy()
+	longer := NewBufferFromBytes([]byte("hunter2hunter2!"))
+	defer longer.Destroy()
+	destroyed := NewBufferFromBytes([]byte("failed"))
+	destroyed.Destroy()
+
+	base := errors.New("base")
+	err := fmt.Errorf("failed to parse key hunter2 (also hunter2hunter2!): %w", base)
+
+	r := RedactError(err, key, longer, destroyed, nil)
+	if r.Error() != "failed to parse key [REDACTED] (also [REDACTED]): base" {
+		t.Error("unexpected message:", r.Error())
+	}
+	if strings.Contains(r.Error(), "hunter2") {
+		t.Error("secret not redacted")
+	}
+	if !errors.Is(r, base) || errors.Unwrap(r) != err {
+		t.Error("original error not wrapped")
+	}
+
+	// The secrets are unaffected and their protection is restored.
+	if !equalTo(key, []byte("hunter2")) || key.IsMutable() {
+		t.Error("secret modified")
+	}
+	key.Protect(false, false)
+	r = RedactError(errors.New("hunter2"), key)
+	if r.Error() != redacted {
+		t.Error("inaccessible secret not redacted; got", r.Error())
+	}
+	if !faults(func() { faultSink = key.Bytes()[0] }) {
+		t.Error("protection not restored")
+	}
+
+	// Messages without secrets are unchanged.
+	if r := RedactError(base, key); r.Error() != "base" {
+		t.Error("unexpected message:", r.Error())
+	}
+	if RedactError(nil, key) != nil {
+		t.Error("expected nil error")
+	}
+}
@@ -0,0 +1,74 @@
+package memguard
+
+import (
+	"sync"
+)
+
+// Global registry of named LockedBuffers.
+var registry = struct {
+	sync.RWMutex
+	buffers map[string]*LockedBuffer
+}{buffers: make(map[string]*LockedBuffer)}
+
+/*
+Register stores a LockedBuffer under a given name so that it can be retrieved elsewhere with Lookup, instead of passing it through every function that needs it. The registry takes ownership of the LockedBuffer: it is destroyed when it is unregistered, when it is replaced by a subsequent call to Register with the same name, or when the session is purged.
+
+It is safe to call Register, Lookup, and Unregister concurrently.
+*/
+func Register(name string, b *LockedBuffer) {
+	registry.Lock()
+	old, ok := registry.buffers[name]
+	registry.buffers[name] = b
+	registry.Unlock()
+
+	// Destroy outside of the lock, as functions registered with OnDestroy may use the registry.
+	if ok && old != b {
+		old.Destroy()
+	}
+}
+
+/*
+Lookup retrieves the LockedBuffer registered under a given name. The boolean value reports whether one was found.
+*/
+func Lookup(name string) (*LockedBuffer, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	b, ok := registry.buffers[name]
+	return b, ok
+}
+
+/*
+Unregister removes the LockedBuffer registered under a given name and destroys it. It does nothing if there is no such LockedBuffer.
+*/
+func Unregister(name string) {
+	registry.Lock()
+	b, ok := registry.buffers[name]
+	delete(registry.buffers, name)
+	registry.Unlock()
+
+	// Destroy outside of the lock, as functions registered with OnDestroy may use the registry.
+	if ok {
+		b.Destroy()
+	}
+}
+
+// Removes all entries from the registry without destroying them.
+func clearRegistry() {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.buffers = make(map[string]*LockedBuffer)
+}
+
+// Removes the entries of the registry that have been destroyed.
+func pruneRegistry() {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for name, b := range registry.buffers {
+		if !b.IsAlive() {
+			delete(registry.buffers, name)
+		}
+	}
+}
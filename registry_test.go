@@ -0,0 +1,110 @@
+package memguard
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	a := NewBufferRandom(32)
+	b := NewBufferRandom(32)
+
+	Register("a", a)
+	Register("b", b)
+
+	// Lookup returns the registered buffers.
+	if v, ok := Lookup("a"); !ok || v != a {
+		t.Error("lookup failed")
+	}
+	if v, ok := Lookup("b"); !ok || v != b {
+		t.Error("lookup failed")
+	}
+	if v, ok := Lookup("c"); ok || v != nil {
+		t.Error("unexpected lookup result", v)
+	}
+
+	// Unregister destroys the buffer.
+	Unregister("a")
+	if _, ok := Lookup("a"); ok {
+		t.Error("buffer should have been unregistered")
+	}
+	if a.IsAlive() {
+		t.Error("unregistered buffer should be destroyed")
+	}
+	Unregister("a") // no-op
+
+	// Replacing destroys the old buffer.
+	c := NewBufferRandom(32)
+	Register("b", c)
+	if b.IsAlive() {
+		t.Error("replaced buffer should be destroyed")
+	}
+	if v, _ := Lookup("b"); v != c {
+		t.Error("lookup failed")
+	}
+	Register("b", c) // re-registering the same buffer keeps it alive
+	if !c.IsAlive() {
+		t.Error("buffer should not be destroyed")
+	}
+
+	// Concurrent access.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, ok := Lookup("b"); !ok || v != c {
+				t.Error("lookup failed")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Purging clears the registry.
+	Purge()
+	if _, ok := Lookup("b"); ok {
+		t.Error("registry was not cleared")
+	}
+	if c.IsAlive() {
+		t.Error("buffer should be destroyed")
+	}
+}
+
+func TestRegistryOnDestroy(t *testing.T) {
+	// Functions called on destruction can use the registry.
+	a := NewBufferRandom(32)
+	a.OnDestroy(func() {
+		if _, ok := Lookup("a"); !ok {
+			t.Error("replacement not registered")
+		}
+		Register("c", NewBufferRandom(8))
+	})
+	b := NewBufferRandom(32)
+	b.OnDestroy(func() {
+		if _, ok := Lookup("a"); ok {
+			t.Error("entry not removed before destruction")
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Register("a", a)
+		Register("a", b)
+		Unregister("a")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock destroying a registered buffer")
+	}
+
+	if a.IsAlive() || b.IsAlive() {
+		t.Error("buffers should be destroyed")
+	}
+	if _, ok := Lookup("c"); !ok {
+		t.Error("buffer registered on destruction is missing")
+	}
+	Unregister("c")
+}
@@ -0,0 +1,296 @@
+package memguard
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/poly1305"
+)
+
+// ErrInvalidSealedData is returned when parsing data that is not a valid serialization of a SealedData object, including one with an unsupported version.
+var ErrInvalidSealedData = errors.New("<memguard::ErrInvalidSealedData> sealed data is malformed or has an unsupported version")
+
+// ErrNonceExhausted is returned when a SealingKey has been used for as many seal operations as its nonces allow.
+var ErrNonceExhausted = errors.New("<memguard::ErrNonceExhausted> key has been used to seal too many times; rotate it")
+
+// Version of the serialization format written by MarshalBinary.
+const sealVersion = 1
+
+/*
+SealedData holds data that has been encrypted and authenticated under a key held in a LockedBuffer, together with metadata, such as a key identifier or a creation time, that is authenticated but not encrypted. Unlike an Enclave it does not depend on the current session, so it can be serialized with MarshalBinary and stored.
+*/
+type SealedData struct {
+	header     []byte // Version and encoded metadata, used as the associated data
+	metadata   map[string]string
+	ciphertext []byte // Nonce followed by the sealed data
+}
+
+/*
+SealWithMetadata encrypts the contents of a LockedBuffer under a 32 byte key using XChaCha20-Poly1305 with a random nonce, binding the given metadata to the ciphertext as associated data. The metadata is encoded deterministically, so the order of the map does not matter, and any modification to it causes Open to fail. The LockedBuffer is left unchanged.
+
+The key is used directly from guarded memory, although the cipher state derived from it is held by the golang.org/x/crypto package for the duration of the call. An error is returned if the key is not 32 bytes long or if either LockedBuffer has been destroyed.
+*/
+func SealWithMetadata(b, key *LockedBuffer, meta map[string]string) (*SealedData, error) {
+	var nonce [chacha20poly1305.NonceSizeX]byte
+	if err := core.Scramble(nonce[:]); err != nil {
+		return nil, err
+	}
+	return seal(b, key, meta, nonce)
+}
+
+/*
+SealingKey wraps a 32 byte key held in a LockedBuffer for use with SealWithKey, which derives every nonce from a random per-SealingKey prefix and a counter instead of choosing it at random. Nonces are then guaranteed never to repeat for as long as the same SealingKey is used, no matter how many seal operations are performed, and the counter enforces a hard limit on how many times the key can be used.
+
+A SealingKey is safe for concurrent use. Every seal operation with a given key should go through the same SealingKey: separate SealingKeys wrapping the same key only have distinct nonces with high probability, as with SealWithMetadata.
+*/
+type SealingKey struct {
+	sync.Mutex
+	key     *LockedBuffer
+	prefix  [chacha20poly1305.NonceSizeX - 8]byte
+	counter uint64
+}
+
+// NewSealingKey creates a SealingKey for a 32 byte key held in a LockedBuffer, with a fresh random nonce prefix. The LockedBuffer is used directly and so must not be destroyed while the SealingKey is in use.
+func NewSealingKey(key *LockedBuffer) (*SealingKey, error) {
+	k := &SealingKey{key: key}
+	if err := core.Scramble(k.prefix[:]); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reserves the next nonce of a SealingKey. A nonce is never handed out twice, even if the seal operation using it fails.
+func (k *SealingKey) nextNonce() (nonce [chacha20poly1305.NonceSizeX]byte, err error) {
+	k.Lock()
+	defer k.Unlock()
+
+	if k.counter == math.MaxUint64 {
+		return nonce, ErrNonceExhausted
+	}
+	copy(nonce[:], k.prefix[:])
+	binary.BigEndian.PutUint64(nonce[len(k.prefix):], k.counter)
+	k.counter++
+	return nonce, nil
+}
+
+/*
+SealWithKey behaves like SealWithMetadata, but takes the next nonce from a SealingKey rather than generating one at random, so that no nonce is ever reused under its key. The result is opened with the key wrapped by the SealingKey, as usual.
+
+ErrNonceExhausted is returned once the SealingKey has been used 2^64 - 1 times, after which the key must be replaced. The other errors are those of SealWithMetadata.
+*/
+func SealWithKey(b *LockedBuffer, key *SealingKey, meta map[string]string) (*SealedData, error) {
+	nonce, err := key.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	return seal(b, key.key, meta, nonce)
+}
+
+// Seals the contents of a LockedBuffer using a given nonce.
+func seal(b, key *LockedBuffer, meta map[string]string, nonce [chacha20poly1305.NonceSizeX]byte) (*SealedData, error) {
+	s := &SealedData{header: encodeSealHeader(meta), metadata: copyMetadata(meta)}
+	if err := b.Access(false, func(data []byte) error {
+		return key.withAEAD(func(aead cipher.AEAD) error {
+			s.ciphertext = aead.Seal(nonce[:], nonce[:], data, s.header)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+/*
+Open authenticates the ciphertext and metadata of a SealedData object and decrypts the data directly into a new immutable LockedBuffer.
+
+If the key is incorrect or either the ciphertext or the metadata has been modified, core.ErrDecryptionFailed is returned.
+*/
+func (s *SealedData) Open(key *LockedBuffer) (*LockedBuffer, error) {
+	b := newNullBuffer()
+	err := key.withAEAD(func(aead cipher.AEAD) error {
+		if len(s.ciphertext) < aead.NonceSize()+aead.Overhead() {
+			return core.ErrDecryptionFailed
+		}
+		nonce, ciphertext := s.ciphertext[:aead.NonceSize()], s.ciphertext[aead.NonceSize():]
+
+		// Decrypt into guarded memory.
+		var out []byte
+		if size := len(ciphertext) - aead.Overhead(); size > 0 {
+			b = NewBuffer(size)
+			out = b.Bytes()
+		}
+		if _, err := aead.Open(out[:0], nonce, ciphertext, s.header); err != nil {
+			b.Destroy()
+			return core.ErrDecryptionFailed
+		}
+		return nil
+	})
+	if err != nil {
+		return newNullBuffer(), err
+	}
+
+	b.Freeze()
+	return b, nil
+}
+
+/*
+Metadata returns a copy of the metadata of a SealedData object. It has only been verified once Open has succeeded.
+*/
+func (s *SealedData) Metadata() map[string]string {
+	return copyMetadata(s.metadata)
+}
+
+/*
+MarshalBinary serializes a SealedData object, including its metadata, into a versioned format that can be parsed with UnmarshalBinary. Only ciphertext and metadata is included, so the result may be stored in the clear.
+*/
+func (s *SealedData) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 4+len(s.header)+len(s.ciphertext))
+	data = appendUint32(data, uint32(len(s.header)))
+	data = append(data, s.header...)
+	return append(data, s.ciphertext...), nil
+}
+
+/*
+UnmarshalBinary parses data produced by MarshalBinary into a SealedData object. ErrInvalidSealedData is returned if the data is malformed or was written by an unsupported version. Its authenticity is only verified by Open.
+*/
+func (s *SealedData) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return ErrInvalidSealedData
+	}
+	n := binary.BigEndian.Uint32(data)
+	if uint64(n) > uint64(len(data)-4) {
+		return ErrInvalidSealedData
+	}
+	header := data[4 : 4+n]
+
+	meta, ok := decodeSealHeader(header)
+	if !ok {
+		return ErrInvalidSealedData
+	}
+
+	s.header = append([]byte(nil), header...)
+	s.metadata = meta
+	s.ciphertext = append([]byte(nil), data[4+n:]...)
+	return nil
+}
+
+/*
+EncodeToString returns the serialized form of a SealedData object, as produced by MarshalBinary, encoded as a base64 string. It holds only ciphertext and metadata and is opened with the same key in any later session, so it may be stored in a text configuration file such as YAML or JSON.
+*/
+func (s *SealedData) EncodeToString() string {
+	data, _ := s.MarshalBinary()
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+/*
+DecodeSealedString parses a string produced by EncodeToString into a SealedData object. ErrInvalidSealedData is returned if the string is not valid base64, if the decoded data is not framed as by MarshalBinary, or if it is too short to hold a nonce and an authenticator. Its authenticity is only verified by Open.
+*/
+func DecodeSealedString(str string) (*SealedData, error) {
+	data, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return nil, ErrInvalidSealedData
+	}
+	s := new(SealedData)
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if len(s.ciphertext) < chacha20poly1305.NonceSizeX+poly1305.TagSize {
+		return nil, ErrInvalidSealedData
+	}
+	return s, nil
+}
+
+// Calls a function with an XChaCha20-Poly1305 instance keyed by the contents of a LockedBuffer.
+func (key *LockedBuffer) withAEAD(fn func(aead cipher.AEAD) error) error {
+	return key.Access(false, func(k []byte) error {
+		if len(k) != chacha20poly1305.KeySize {
+			return core.ErrInvalidKeyLength
+		}
+		aead, err := chacha20poly1305.NewX(k)
+		if err != nil {
+			return err
+		}
+		return fn(aead)
+	})
+}
+
+// Encodes the version and metadata as the associated data: the version byte, the number of entries, and then each key and value prefixed by its length, in sorted order of keys.
+func encodeSealHeader(meta map[string]string) []byte {
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	header := []byte{sealVersion}
+	header = appendUint32(header, uint32(len(keys)))
+	for _, k := range keys {
+		header = appendUint32(header, uint32(len(k)))
+		header = append(header, k...)
+		header = appendUint32(header, uint32(len(meta[k])))
+		header = append(header, meta[k]...)
+	}
+	return header
+}
+
+// Decodes a header produced by encodeSealHeader, rejecting any other encoding of the same metadata.
+func decodeSealHeader(header []byte) (map[string]string, bool) {
+	if len(header) < 5 || header[0] != sealVersion {
+		return nil, false
+	}
+	count := binary.BigEndian.Uint32(header[1:])
+	rest := header[5:]
+
+	next := func() (string, bool) {
+		if len(rest) < 4 {
+			return "", false
+		}
+		n := binary.BigEndian.Uint32(rest)
+		if uint64(n) > uint64(len(rest)-4) {
+			return "", false
+		}
+		str := string(rest[4 : 4+n])
+		rest = rest[4+n:]
+		return str, true
+	}
+
+	meta := make(map[string]string)
+	prev := ""
+	for i := uint32(0); i < count; i++ {
+		k, ok := next()
+		if !ok || (i > 0 && k <= prev) {
+			return nil, false
+		}
+		v, ok := next()
+		if !ok {
+			return nil, false
+		}
+		meta[k] = v
+		prev = k
+	}
+	if len(rest) != 0 {
+		return nil, false
+	}
+	return meta, true
+}
+
+func copyMetadata(meta map[string]string) map[string]string {
+	c := make(map[string]string, len(meta))
+	for k, v := range meta {
+		c[k] = v
+	}
+	return c
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
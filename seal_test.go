@@ -0,0 +1,295 @@
+package memguard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/poly1305"
+)
+
+func TestSealWithMetadata(t *testing.T) {
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+	meta := map[string]string{"key-id": "1", "created": "2020-01-01", "": "empty"}
+
+	s, err := SealWithMetadata(b, key, meta)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("yellow submarine")) {
+		t.Error("buffer was modified")
+	}
+	if !reflect.DeepEqual(s.Metadata(), meta) {
+		t.Error("unexpected metadata; got", s.Metadata())
+	}
+
+	// The metadata is encoded independently of map order.
+	if !bytes.Equal(s.header, encodeSealHeader(map[string]string{"": "empty", "created": "2020-01-01", "key-id": "1"})) {
+		t.Error("metadata encoding is not deterministic")
+	}
+
+	// Round trip through the serialized form.
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	var r SealedData
+	if err := r.UnmarshalBinary(data); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !reflect.DeepEqual(r.Metadata(), meta) {
+		t.Error("unexpected metadata; got", r.Metadata())
+	}
+	o, err := r.Open(key)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(o, []byte("yellow submarine")) {
+		t.Error("unexpected plaintext; got", o.Bytes())
+	}
+	if o.IsMutable() {
+		t.Error("expected opened buffer to be immutable")
+	}
+	o.Destroy()
+
+	// Modifying the returned metadata does not affect the object.
+	m := s.Metadata()
+	m["key-id"] = "2"
+	if s.Metadata()["key-id"] != "1" {
+		t.Error("metadata was modified through returned map")
+	}
+
+	// Wrong key.
+	wrong := NewBufferRandom(32)
+	defer wrong.Destroy()
+	if o, err := s.Open(wrong); err != core.ErrDecryptionFailed || o.Size() != 0 {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+
+	// Invalid key length.
+	short := NewBufferRandom(16)
+	defer short.Destroy()
+	if _, err := SealWithMetadata(b, short, meta); err != core.ErrInvalidKeyLength {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+	if _, err := s.Open(short); err != core.ErrInvalidKeyLength {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+
+	// Destroyed inputs.
+	d := NewBufferRandom(32)
+	d.Destroy()
+	if _, err := SealWithMetadata(d, key, meta); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if _, err := SealWithMetadata(b, d, meta); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if _, err := s.Open(d); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestSealWithMetadataTampering(t *testing.T) {
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+
+	s, err := SealWithMetadata(b, key, map[string]string{"role": "user"})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	data, _ := s.MarshalBinary()
+
+	// Flip every byte in turn; each must be rejected either when parsing or when opening.
+	for i := range data {
+		tampered := append([]byte(nil), data...)
+		tampered[i] ^= 1
+
+		var r SealedData
+		if err := r.UnmarshalBinary(tampered); err != nil {
+			if err != ErrInvalidSealedData {
+				t.Error("expected ErrInvalidSealedData; got", err)
+			}
+			continue
+		}
+		if _, err := r.Open(key); err != core.ErrDecryptionFailed {
+			t.Error("expected ErrDecryptionFailed at byte", i, "; got", err)
+		}
+	}
+
+	// Replacing the metadata of an otherwise valid object also fails.
+	forged := &SealedData{header: encodeSealHeader(map[string]string{"role": "admin"}), ciphertext: s.ciphertext}
+	if _, err := forged.Open(key); err != core.ErrDecryptionFailed {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+}
+
+func TestSealedDataUnmarshalBinary(t *testing.T) {
+	valid := encodeSealHeader(map[string]string{"a": "1", "b": "2"})
+	frame := func(header []byte) []byte {
+		return append(appendUint32(nil, uint32(len(header))), header...)
+	}
+
+	unsorted := []byte{sealVersion, 0, 0, 0, 2, 0, 0, 0, 1, 'b', 0, 0, 0, 0, 0, 0, 0, 1, 'a', 0, 0, 0, 0}
+	badVersion := append([]byte{sealVersion + 1}, valid[1:]...)
+
+	for _, data := range [][]byte{
+		nil,
+		{0, 0},
+		{0, 0, 0, 10, 1},
+		frame(nil),
+		frame(badVersion),
+		frame(valid[:len(valid)-1]),
+		frame(append(append([]byte(nil), valid...), 0)),
+		frame(unsorted),
+	} {
+		var s SealedData
+		if err := s.UnmarshalBinary(data); err != ErrInvalidSealedData {
+			t.Error("expected ErrInvalidSealedData for", data, "; got", err)
+		}
+	}
+
+	var s SealedData
+	if err := s.UnmarshalBinary(frame(valid)); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+	if _, err := s.Open(key); err != core.ErrDecryptionFailed {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+}
+
+func TestSealedDataString(t *testing.T) {
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+
+	s, err := SealWithMetadata(b, key, map[string]string{"key-id": "1"})
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	str := s.EncodeToString()
+	if strings.Contains(str, base64.StdEncoding.EncodeToString([]byte("yellow submarine"))) {
+		t.Error("plaintext found in encoding")
+	}
+
+	// Round trip.
+	r, err := DecodeSealedString(str)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !reflect.DeepEqual(r.Metadata(), s.Metadata()) {
+		t.Error("unexpected metadata; got", r.Metadata())
+	}
+	o, err := r.Open(key)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(o, []byte("yellow submarine")) {
+		t.Error("unexpected plaintext; got", o.Bytes())
+	}
+	o.Destroy()
+
+	// Malformed strings.
+	data, _ := s.MarshalBinary()
+	header := data[:len(data)-len(s.ciphertext)]
+	short := append(append([]byte(nil), header...), make([]byte, chacha20poly1305.NonceSizeX+poly1305.TagSize-1)...)
+	for _, str := range []string{
+		"",
+		"not base64!",
+		"AAAA=",
+		base64.StdEncoding.EncodeToString([]byte{0, 0}),
+		base64.StdEncoding.EncodeToString(data[:3]),
+		base64.StdEncoding.EncodeToString(header),
+		base64.StdEncoding.EncodeToString(short),
+	} {
+		if r, err := DecodeSealedString(str); err != ErrInvalidSealedData || r != nil {
+			t.Error("expected ErrInvalidSealedData for", str, "; got", err)
+		}
+	}
+
+	// Modified strings decode but fail to open.
+	data[len(data)-1] ^= 0xff
+	r, err = DecodeSealedString(base64.StdEncoding.EncodeToString(data))
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if _, err := r.Open(key); err != core.ErrDecryptionFailed {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+}
+
+func TestSealWithKey(t *testing.T) {
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+	k, err := NewSealingKey(key)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+
+	// Every seal uses a distinct nonce and opens with the wrapped key.
+	nonces := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		s, err := SealWithKey(b, k, map[string]string{"n": "1"})
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		nonce := string(s.ciphertext[:chacha20poly1305.NonceSizeX])
+		if nonces[nonce] {
+			t.Error("nonce reused at seal", i)
+		}
+		nonces[nonce] = true
+
+		if i%100 == 0 {
+			o, err := s.Open(key)
+			if err != nil || !equalTo(o, []byte("yellow submarine")) {
+				t.Error("failed to open sealed data:", err)
+			}
+			o.Destroy()
+		}
+	}
+
+	// Failed seals still consume a nonce.
+	d := NewBuffer(32)
+	d.Destroy()
+	before := k.counter
+	if _, err := SealWithKey(d, k, nil); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if k.counter != before+1 {
+		t.Error("nonce not consumed by failed seal")
+	}
+
+	// The key is refused once the counter is exhausted.
+	k.counter = math.MaxUint64 - 1
+	if _, err := SealWithKey(b, k, nil); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	for i := 0; i < 2; i++ {
+		if s, err := SealWithKey(b, k, nil); err != ErrNonceExhausted || s != nil {
+			t.Error("expected ErrNonceExhausted; got", err)
+		}
+	}
+
+	// Invalid keys.
+	short := NewBufferRandom(16)
+	defer short.Destroy()
+	k, _ = NewSealingKey(short)
+	if _, err := SealWithKey(b, k, nil); err != core.ErrInvalidKeyLength {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+}
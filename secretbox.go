@@ -0,0 +1,56 @@
+package memguard
+
+import (
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+SecretboxSeal encrypts and authenticates a message with NaCl's secretbox construction using a 32 byte key held in a LockedBuffer. A random 24 byte nonce is generated and prepended to the returned ciphertext, which is therefore core.Overhead bytes longer than the message.
+
+The key is used directly from guarded memory. An error is returned if the key is not 32 bytes long or if it has been destroyed.
+*/
+func (key *LockedBuffer) SecretboxSeal(message []byte) ([]byte, error) {
+	var ciphertext []byte
+	err := key.Access(false, func(k []byte) (err error) {
+		ciphertext, err = core.Encrypt(message, k)
+		return err
+	})
+	return ciphertext, err
+}
+
+/*
+SecretboxOpen authenticates and decrypts a ciphertext produced by SecretboxSeal, or by any secretbox implementation that prepends the nonce. The plaintext is written directly into a new immutable LockedBuffer.
+
+If the key is incorrect or the ciphertext has been modified, core.ErrDecryptionFailed is returned.
+*/
+func (key *LockedBuffer) SecretboxOpen(ciphertext []byte) (*LockedBuffer, error) {
+	if !key.IsAlive() {
+		return newNullBuffer(), core.ErrBufferExpired
+	}
+	if key.Size() != 32 {
+		return newNullBuffer(), core.ErrInvalidKeyLength
+	}
+	if len(ciphertext) < core.Overhead {
+		return newNullBuffer(), core.ErrDecryptionFailed
+	}
+
+	// An empty message has no data to return.
+	if len(ciphertext) == core.Overhead {
+		return newNullBuffer(), key.secretboxOpen(ciphertext, nil)
+	}
+
+	b := NewBuffer(len(ciphertext) - core.Overhead)
+	if err := key.secretboxOpen(ciphertext, b.Bytes()); err != nil {
+		b.Destroy()
+		return newNullBuffer(), err
+	}
+	b.Freeze()
+	return b, nil
+}
+
+func (key *LockedBuffer) secretboxOpen(ciphertext, output []byte) error {
+	return key.Access(false, func(k []byte) error {
+		_, err := core.Decrypt(ciphertext, k, output)
+		return err
+	})
+}
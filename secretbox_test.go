@@ -0,0 +1,119 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+func TestSecretboxSeal(t *testing.T) {
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+
+	message := []byte("yellow submarine")
+	ciphertext, err := key.SecretboxSeal(message)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(ciphertext) != len(message)+core.Overhead {
+		t.Error("ciphertext has incorrect length")
+	}
+
+	// Interoperates with secretbox directly.
+	var nonce [24]byte
+	copy(nonce[:], ciphertext[:24])
+	plaintext, ok := secretbox.Open(nil, ciphertext[24:], &nonce, key.ByteArray32())
+	if !ok || !bytes.Equal(plaintext, message) {
+		t.Error("secretbox could not open ciphertext")
+	}
+
+	// Nonces are random.
+	other, err := key.SecretboxSeal(message)
+	if err != nil {
+		t.Error(err)
+	}
+	if bytes.Equal(other[:24], ciphertext[:24]) {
+		t.Error("nonce was repeated")
+	}
+
+	// Invalid keys.
+	short := NewBufferRandom(16)
+	if _, err := short.SecretboxSeal(message); err != core.ErrInvalidKeyLength {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+	short.Destroy()
+	if _, err := short.SecretboxSeal(message); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestSecretboxOpen(t *testing.T) {
+	key := NewBufferRandom(32)
+	defer key.Destroy()
+
+	ciphertext, err := key.SecretboxSeal([]byte("yellow submarine"))
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Round trip.
+	b, err := key.SecretboxOpen(ciphertext)
+	if err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(b.Bytes(), []byte("yellow submarine")) {
+		t.Error("plaintext does not match")
+	}
+	if b.IsMutable() {
+		t.Error("plaintext buffer should be immutable")
+	}
+	b.Destroy()
+
+	// Tampered ciphertext.
+	for i := range ciphertext {
+		ciphertext[i] ^= 0x01
+		b, err := key.SecretboxOpen(ciphertext)
+		if err != core.ErrDecryptionFailed {
+			t.Error("expected ErrDecryptionFailed; got", err)
+		}
+		if b.IsAlive() {
+			t.Error("expected null buffer")
+		}
+		ciphertext[i] ^= 0x01
+	}
+	if _, err := key.SecretboxOpen(ciphertext[:core.Overhead-1]); err != core.ErrDecryptionFailed {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+
+	// Wrong key.
+	other := NewBufferRandom(32)
+	if _, err := other.SecretboxOpen(ciphertext); err != core.ErrDecryptionFailed {
+		t.Error("expected ErrDecryptionFailed; got", err)
+	}
+	other.Destroy()
+
+	// Empty message.
+	ciphertext, err = key.SecretboxSeal(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	b, err = key.SecretboxOpen(ciphertext)
+	if err != nil {
+		t.Error(err)
+	}
+	if b.Size() != 0 {
+		t.Error("expected empty plaintext")
+	}
+
+	// Invalid keys.
+	short := NewBufferRandom(16)
+	if _, err := short.SecretboxOpen(ciphertext); err != core.ErrInvalidKeyLength {
+		t.Error("expected ErrInvalidKeyLength; got", err)
+	}
+	short.Destroy()
+	if _, err := short.SecretboxOpen(ciphertext); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,52 @@
+package memguard
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrInvalidDestination is returned when UnmarshalSecrets is given something other than a non-nil pointer to a struct, or when a tagged field of that struct cannot hold a *LockedBuffer.
+var ErrInvalidDestination = errors.New("<memguard::ErrInvalidDestination> destination must be a pointer to a struct whose tagged fields are exported *LockedBuffer values")
+
+// The struct tag key used to name the secret that populates a field.
+const secretTag = "memguard"
+
+var lockedBufferType = reflect.TypeOf((*LockedBuffer)(nil))
+
+/*
+UnmarshalSecrets populates the *LockedBuffer fields of a struct from a map of raw secret values, such as might be read from a configuration file or the environment. A field is populated when it carries a tag of the form `memguard:"name"` and src contains an entry with that name. Fields without the tag are ignored, as are tagged fields whose name is not present in src.
+
+Each value is moved into an immutable LockedBuffer with NewBufferFromBytes, and so every source slice that is used is wiped. Values in src that no field refers to are left untouched.
+
+ErrInvalidDestination is returned if dst is not a non-nil pointer to a struct, or if a tagged field is unexported or not of type *LockedBuffer. The struct is checked before anything is copied, so no fields are modified and no sources are wiped in this case.
+*/
+func UnmarshalSecrets(dst interface{}, src map[string][]byte) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidDestination
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	// Find and validate the tagged fields.
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		name, ok := t.Field(i).Tag.Lookup(secretTag)
+		if !ok {
+			continue
+		}
+		if t.Field(i).Type != lockedBufferType || !v.Field(i).CanSet() {
+			return ErrInvalidDestination
+		}
+		if _, ok := src[name]; ok {
+			fields = append(fields, i)
+		}
+	}
+
+	// Move the values into guarded memory.
+	for _, i := range fields {
+		v.Field(i).Set(reflect.ValueOf(NewBufferFromBytes(src[t.Field(i).Tag.Get(secretTag)])))
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUnmarshalSecrets(t *testing.T) {
+	var config struct {
+		APIKey   *LockedBuffer `memguard:"api_key"`
+		Password *LockedBuffer `memguard:"password"`
+		Missing  *LockedBuffer `memguard:"missing"`
+		Host     string
+		Other    *LockedBuffer
+	}
+	config.Host = "localhost"
+
+	apiKey := []byte("yellow submarine")
+	password := []byte("hunter2")
+	unused := []byte("unused")
+	src := map[string][]byte{
+		"api_key":  apiKey,
+		"password": password,
+		"unused":   unused,
+	}
+
+	if err := UnmarshalSecrets(&config, src); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	defer config.APIKey.Destroy()
+	defer config.Password.Destroy()
+
+	// Tagged fields are populated with immutable buffers.
+	if !equalTo(config.APIKey, []byte("yellow submarine")) {
+		t.Error("api key not populated; got", config.APIKey.Bytes())
+	}
+	if !equalTo(config.Password, []byte("hunter2")) {
+		t.Error("password not populated; got", config.Password.Bytes())
+	}
+	if config.APIKey.IsMutable() || config.Password.IsMutable() {
+		t.Error("expected populated buffers to be immutable")
+	}
+
+	// Sources that were used are wiped.
+	if !bytes.Equal(apiKey, make([]byte, len(apiKey))) || !bytes.Equal(password, make([]byte, len(password))) {
+		t.Error("source values not wiped")
+	}
+	if !bytes.Equal(unused, []byte("unused")) {
+		t.Error("unused source value was modified")
+	}
+
+	// Everything else is left alone.
+	if config.Missing != nil || config.Other != nil {
+		t.Error("unexpected fields populated")
+	}
+	if config.Host != "localhost" {
+		t.Error("non-secret field modified")
+	}
+}
+
+func TestUnmarshalSecretsInvalid(t *testing.T) {
+	value := []byte("secret")
+	src := map[string][]byte{"key": value}
+
+	var ok struct {
+		Key *LockedBuffer `memguard:"key"`
+	}
+	var wrongType struct {
+		Key []byte `memguard:"key"`
+	}
+	var unexported struct {
+		key *LockedBuffer `memguard:"key"`
+	}
+	var partial struct {
+		First  *LockedBuffer `memguard:"key"`
+		Second string        `memguard:"other"`
+	}
+
+	for _, dst := range []interface{}{nil, ok, (*struct{})(nil), new(int), &wrongType, &unexported, &partial} {
+		if err := UnmarshalSecrets(dst, src); err != ErrInvalidDestination {
+			t.Error("expected ErrInvalidDestination; got", err)
+		}
+	}
+
+	// Nothing is modified on error.
+	if partial.First != nil {
+		t.Error("field populated on error")
+	}
+	if !bytes.Equal(value, []byte("secret")) {
+		t.Error("source wiped on error")
+	}
+	_ = unexported.key
+}
@@ -0,0 +1,129 @@
+package memguard
+
+import (
+	"sync"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/blake2b"
+)
+
+/*
+SecureMap is a map that is keyed by the contents of LockedBuffer objects without the plaintext of any key being held outside of guarded memory. Keys are located by a keyed hash whose salt is generated randomly for each map, so identical secrets hash differently in different maps, and entries whose hashes collide are told apart by a constant-time comparison against a read-only copy of the key held in guarded memory.
+
+A SecureMap is safe for concurrent use. It must be created with NewSecureMap and should be destroyed with Destroy once it is no longer needed.
+*/
+type SecureMap struct {
+	sync.Mutex
+	salt    *LockedBuffer
+	buckets map[[32]byte][]*secureMapEntry
+
+	hash func(salt, key []byte) [32]byte
+}
+
+type secureMapEntry struct {
+	key   *LockedBuffer
+	value interface{}
+}
+
+// NewSecureMap creates a new empty SecureMap with a fresh random salt.
+func NewSecureMap() *SecureMap {
+	return &SecureMap{
+		salt:    NewBufferRandom(32),
+		buckets: make(map[[32]byte][]*secureMapEntry),
+		hash:    saltedHash,
+	}
+}
+
+/*
+Set associates a value with the contents of a LockedBuffer, replacing any value previously associated with an equal key. The key is copied into a new read-only LockedBuffer, and so it may be modified or destroyed afterwards without affecting the map.
+
+Set does nothing if the key has been destroyed or if the map has been destroyed.
+*/
+func (m *SecureMap) Set(key *LockedBuffer, value interface{}) {
+	m.Lock()
+	defer m.Unlock()
+
+	h, ok := m.lookup(key)
+	if !ok {
+		return
+	}
+	if e := m.find(h, key); e != nil {
+		e.value = value
+		return
+	}
+
+	c, err := key.ToReadOnlyClone()
+	if err != nil {
+		return
+	}
+	m.buckets[h] = append(m.buckets[h], &secureMapEntry{key: c, value: value})
+}
+
+/*
+Get returns the value associated with the contents of a LockedBuffer, and whether such a value was found. It always reports false for a key that has been destroyed or if the map has been destroyed.
+*/
+func (m *SecureMap) Get(key *LockedBuffer) (interface{}, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	h, ok := m.lookup(key)
+	if !ok {
+		return nil, false
+	}
+	if e := m.find(h, key); e != nil {
+		return e.value, true
+	}
+	return nil, false
+}
+
+/*
+Destroy destroys the copies of every key held by a SecureMap along with its salt, and removes all of its entries. The map is empty and unusable afterwards.
+*/
+func (m *SecureMap) Destroy() {
+	m.Lock()
+	defer m.Unlock()
+
+	for h, bucket := range m.buckets {
+		for _, e := range bucket {
+			e.key.Destroy()
+		}
+		delete(m.buckets, h)
+	}
+	m.salt.Destroy()
+}
+
+// Computes the bucket of a key. Must be called with the map locked.
+func (m *SecureMap) lookup(key *LockedBuffer) (h [32]byte, ok bool) {
+	err := m.salt.Access(false, func(salt []byte) error {
+		return key.Access(false, func(k []byte) error {
+			h = m.hash(salt, k)
+			return nil
+		})
+	})
+	return h, err == nil
+}
+
+// Finds the entry of a bucket with a key equal to the given one. Must be called with the map locked.
+func (m *SecureMap) find(h [32]byte, key *LockedBuffer) *secureMapEntry {
+	var found *secureMapEntry
+	for _, e := range m.buckets[h] {
+		key.Access(false, func(k []byte) error {
+			if equal, _ := e.key.EqualTo(k); equal {
+				found = e
+			}
+			return nil
+		})
+	}
+	return found
+}
+
+// Computes a keyed Blake2b hash of a key.
+func saltedHash(salt, key []byte) (h [32]byte) {
+	mac, err := blake2b.New256(salt)
+	if err != nil {
+		core.Panic(err) // Only returned for keys longer than 64 bytes.
+	}
+	mac.Write(key)
+	mac.Sum(h[:0])
+	return h
+}
@@ -0,0 +1,115 @@
+package memguard
+
+import (
+	"testing"
+)
+
+func TestSecureMap(t *testing.T) {
+	m := NewSecureMap()
+
+	a := NewBufferFromBytes([]byte("yellow submarine"))
+	defer a.Destroy()
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+	c := NewBufferFromBytes([]byte("orange submarine"))
+	defer c.Destroy()
+
+	m.Set(a, 1)
+	if v, ok := m.Get(b); !ok || v != 1 {
+		t.Error("expected 1 for equal key; got", v, ok)
+	}
+	if v, ok := m.Get(c); ok {
+		t.Error("unexpected value for missing key:", v)
+	}
+
+	// Setting an equal key replaces the value.
+	m.Set(b, 2)
+	if v, ok := m.Get(a); !ok || v != 2 {
+		t.Error("expected 2; got", v, ok)
+	}
+	if len(m.buckets) != 1 {
+		t.Error("expected one entry; got", len(m.buckets))
+	}
+
+	// The map holds its own copy of the key.
+	k := NewBufferFromBytes([]byte("ephemeral"))
+	m.Set(k, 3)
+	k.Destroy()
+	k = NewBufferFromBytes([]byte("ephemeral"))
+	if v, ok := m.Get(k); !ok || v != 3 {
+		t.Error("expected 3 after original key destroyed; got", v, ok)
+	}
+	k.Destroy()
+
+	// Destroyed keys are ignored.
+	d := NewBufferRandom(32)
+	d.Destroy()
+	m.Set(d, 4)
+	if _, ok := m.Get(d); ok {
+		t.Error("unexpected value for destroyed key")
+	}
+
+	// Identical secrets hash differently in different maps.
+	n := NewSecureMap()
+	defer n.Destroy()
+	hm, _ := m.lookup(a)
+	hn, _ := n.lookup(a)
+	if hm == hn {
+		t.Error("hashes equal across maps")
+	}
+
+	var copies []*LockedBuffer
+	for _, bucket := range m.buckets {
+		for _, e := range bucket {
+			copies = append(copies, e.key)
+		}
+	}
+	m.Destroy()
+	for _, c := range copies {
+		if c.IsAlive() {
+			t.Error("key copy not destroyed")
+		}
+	}
+	if _, ok := m.Get(a); ok {
+		t.Error("unexpected value after destroy")
+	}
+	m.Set(a, 5)
+	if len(m.buckets) != 0 {
+		t.Error("entry added after destroy")
+	}
+}
+
+func TestSecureMapCollision(t *testing.T) {
+	m := NewSecureMap()
+	defer m.Destroy()
+	m.hash = func(salt, key []byte) (h [32]byte) {
+		return h
+	}
+
+	a := NewBufferFromBytes([]byte("yellow submarine"))
+	defer a.Destroy()
+	b := NewBufferFromBytes([]byte("orange submarine"))
+	defer b.Destroy()
+	c := NewBufferFromBytes([]byte("purple submarine"))
+	defer c.Destroy()
+
+	m.Set(a, "a")
+	m.Set(b, "b")
+	if len(m.buckets) != 1 || len(m.buckets[[32]byte{}]) != 2 {
+		t.Error("expected both keys in one bucket")
+	}
+	if v, ok := m.Get(a); !ok || v != "a" {
+		t.Error("expected a; got", v, ok)
+	}
+	if v, ok := m.Get(b); !ok || v != "b" {
+		t.Error("expected b; got", v, ok)
+	}
+	if v, ok := m.Get(c); ok {
+		t.Error("unexpected value for colliding missing key:", v)
+	}
+
+	m.Set(b, "B")
+	if v, _ := m.Get(b); v != "B" || len(m.buckets[[32]byte{}]) != 2 {
+		t.Error("expected value to be replaced in place; got", v)
+	}
+}
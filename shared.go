@@ -0,0 +1,37 @@
+package memguard
+
+import (
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+NewShared creates a mutable data container of the specified size whose data is held in anonymous shared memory, along with a file descriptor referring to that memory. The descriptor can be passed to another process, such as a pre-forked child or one started with os/exec through ExtraFiles, which can then attach to the same locked memory with AttachShared. This allows a secret to be shared without writing it to a pipe or socket. It is only supported on Linux.
+
+The descriptor is owned by the LockedBuffer and is closed when it is destroyed, so the LockedBuffer must be kept alive until the other process has attached. Destroying the LockedBuffer in either process wipes the data in both, after which the other process should destroy its own. The size of the LockedBuffer cannot be changed. A size of less than one returns a null buffer.
+*/
+func NewShared(length int) (*LockedBuffer, uintptr, error) {
+	buf, fd, err := core.NewSharedBuffer(length)
+	if err != nil {
+		if err == core.ErrNullBuffer {
+			return newNullBuffer(), 0, nil
+		}
+		return newNullBuffer(), 0, err
+	}
+	return newBuffer(buf), fd, nil
+}
+
+/*
+AttachShared creates a LockedBuffer backed by shared memory created by NewShared in another process, given a descriptor referring to it and the length that it was created with. Each side has its own guard pages around the shared data. core.ErrSharedSize is returned if the memory was created with a different length.
+
+The descriptor is not taken over by the LockedBuffer and may be closed once AttachShared returns. A length of less than one returns a null buffer.
+*/
+func AttachShared(fd uintptr, length int) (*LockedBuffer, error) {
+	buf, err := core.AttachSharedBuffer(fd, length)
+	if err != nil {
+		if err == core.ErrNullBuffer {
+			return newNullBuffer(), nil
+		}
+		return newNullBuffer(), err
+	}
+	return newBuffer(buf), nil
+}
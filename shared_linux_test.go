@@ -0,0 +1,69 @@
+// +build linux
+
+package memguard
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/sys/unix"
+)
+
+func TestShared(t *testing.T) {
+	// If we're within the testing subprocess, read the secret and reply.
+	if os.Getenv("WITHIN_SUBPROCESS") == "1" {
+		b, err := AttachShared(3, 16)
+		if err != nil {
+			SafePanic(err)
+		}
+		if equalTo(b, []byte("yellow submarine")) {
+			os.Stdout.WriteString("secret received\n")
+		}
+		b.Copy([]byte("purple submarine"))
+		return
+	}
+
+	b, fd, err := NewShared(16)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	defer b.Destroy()
+	b.Copy([]byte("yellow submarine"))
+
+	// Pass a duplicate of the descriptor to a child process, where it becomes descriptor 3.
+	dup, err := unix.Dup(int(fd))
+	if err != nil {
+		t.Error(err)
+	}
+	f := os.NewFile(uintptr(dup), "shared")
+	defer f.Close()
+	cmd := exec.Command(os.Args[0], "-test.run=TestShared$")
+	cmd.Env = append(os.Environ(), "WITHIN_SUBPROCESS=1")
+	cmd.ExtraFiles = []*os.File{f}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Error("subprocess failed:", err, string(out))
+	}
+	if !strings.Contains(string(out), "secret received") {
+		t.Error("child did not read the secret:", string(out))
+	}
+	if !equalTo(b, []byte("purple submarine")) {
+		t.Error("child's write not visible; got", string(b.Bytes()))
+	}
+
+	// Lengths must agree exactly.
+	for _, length := range []int{b.Size() - 1, b.Size() + 1, 8192} {
+		if c, err := AttachShared(fd, length); err != core.ErrSharedSize || c.IsAlive() {
+			t.Error("expected ErrSharedSize for length", length, "got", err)
+		}
+	}
+	if c, err := AttachShared(fd, 0); err != nil || c.IsAlive() {
+		t.Error("expected null buffer; got", err)
+	}
+	if c, _, err := NewShared(0); err != nil || c.IsAlive() {
+		t.Error("expected null buffer; got", err)
+	}
+}
@@ -33,7 +33,8 @@ func CatchSignal(f func(os.Signal), signals ...os.Signal) {
 	create.Do(func() {
 		// Start a goroutine to listen on the channels.
 		go func() {
-			var handler func(os.Signal)
+			// Wait for the initial handler before accepting signals.
+			handler := <-sigfunc
 			for {
 				select {
 				case signal := <-listener:
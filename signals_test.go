@@ -82,3 +82,24 @@ func TestCatchInterrupt(t *testing.T) {
 		t.Error("Wanted exit code 1, got", err.ExitCode(), "err:", err)
 	}
 }
+
+func TestCatchSignalPending(t *testing.T) {
+	if os.Getenv("WITHIN_SUBPROCESS") == "1" {
+		// Queue a signal before any handler has been given
+		listener <- os.Interrupt
+
+		// The handler must be in place before the signal is handled
+		CatchSignal(func(s os.Signal) {})
+		select {}
+	}
+
+	// Construct the subprocess with its initial state
+	cmd := exec.Command(os.Args[0], "-test.run=TestCatchSignalPending")
+	cmd.Env = append(os.Environ(), "WITHIN_SUBPROCESS=1")
+
+	// A nil handler would panic and exit with code 2
+	err := cmd.Run().(*exec.ExitError)
+	if err.ExitCode() != 1 {
+		t.Error("Wanted exit code 1, got", err.ExitCode(), "err:", err)
+	}
+}
@@ -0,0 +1,76 @@
+package memguard
+
+import (
+	"database/sql/driver"
+	"errors"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrPlaintextValue is returned when the contents of a LockedBuffer would be passed to a database driver without being explicitly requested.
+var ErrPlaintextValue = errors.New("<memguard::ErrPlaintextValue> refusing to pass the contents of a LockedBuffer to a database driver; use UnsafeValue")
+
+// ErrUnsupportedScanType is returned when a database driver returns a value that cannot be stored in a LockedBuffer.
+var ErrUnsupportedScanType = errors.New("<memguard::ErrUnsupportedScanType> only []byte, string, and nil values can be scanned")
+
+/*
+Value implements the driver.Valuer interface so that a LockedBuffer cannot be written to a database by accident, for example by passing it as a query argument where a hash was intended. It always returns ErrPlaintextValue. Use UnsafeValue to store the contents deliberately.
+*/
+func (b *LockedBuffer) Value() (driver.Value, error) {
+	return nil, ErrPlaintextValue
+}
+
+/*
+UnsafeValue returns a copy of the contents of a LockedBuffer for passing to a database driver. The copy is made in ordinary memory and is retained by the driver for as long as it needs it, so this should only be used when the plaintext really has to be stored.
+
+An error is returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) UnsafeValue() (driver.Value, error) {
+	var value []byte
+	err := b.Access(false, func(data []byte) error {
+		value = make([]byte, len(data))
+		copy(value, data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+/*
+Scan implements the sql.Scanner interface so that a column can be read straight into guarded memory:
+
+	secret := new(memguard.LockedBuffer)
+	err := row.Scan(secret)
+
+Any existing contents of the LockedBuffer are destroyed and replaced by an immutable copy of the value. A []byte value is wiped after it has been copied, but a string value cannot be wiped and so remains in ordinary memory. A NULL value leaves a null buffer. ErrUnsupportedScanType is returned for any other type, in which case the LockedBuffer is left unchanged.
+*/
+func (b *LockedBuffer) Scan(src interface{}) error {
+	if b == nil {
+		return core.ErrBufferExpired
+	}
+
+	var c *LockedBuffer
+	switch v := src.(type) {
+	case []byte:
+		c = NewBufferFromBytes(v)
+	case string:
+		c = newNullBuffer()
+		if len(v) > 0 {
+			c = NewBuffer(len(v))
+			copy(c.Bytes(), v)
+			c.Freeze()
+		}
+	case nil:
+		c = newNullBuffer()
+	default:
+		return ErrUnsupportedScanType
+	}
+
+	if b.Buffer != nil {
+		b.Destroy()
+	}
+	*b = *c
+	return nil
+}
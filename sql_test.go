@@ -0,0 +1,93 @@
+package memguard
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// Ensure the database/sql interfaces are implemented.
+var (
+	_ driver.Valuer = (*LockedBuffer)(nil)
+	_ sql.Scanner   = (*LockedBuffer)(nil)
+)
+
+func TestValue(t *testing.T) {
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+	defer b.Destroy()
+
+	if v, err := b.Value(); err != ErrPlaintextValue || v != nil {
+		t.Error("expected ErrPlaintextValue; got", v, err)
+	}
+
+	v, err := b.UnsafeValue()
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !bytes.Equal(v.([]byte), []byte("yellow submarine")) {
+		t.Error("unexpected value", v)
+	}
+	v.([]byte)[0] = 'Y'
+	if !equalTo(b, []byte("yellow submarine")) {
+		t.Error("value shares memory with the buffer")
+	}
+
+	b.Destroy()
+	if _, err := b.UnsafeValue(); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	// Scanning into a zero LockedBuffer.
+	b := new(LockedBuffer)
+	src := []byte("yellow submarine")
+	if err := b.Scan(src); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("yellow submarine")) || b.IsMutable() {
+		t.Error("unexpected buffer", b.String())
+	}
+	if !bytes.Equal(src, make([]byte, 16)) {
+		t.Error("source not wiped")
+	}
+
+	// Scanning again replaces the contents and destroys the old buffer.
+	old := *b
+	if err := b.Scan("purple"); err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("purple")) || b.IsMutable() {
+		t.Error("unexpected buffer", b.String())
+	}
+	if old.IsAlive() {
+		t.Error("old buffer not destroyed")
+	}
+
+	// Unsupported types leave the buffer unchanged.
+	if err := b.Scan(int64(42)); err != ErrUnsupportedScanType {
+		t.Error("expected ErrUnsupportedScanType; got", err)
+	}
+	if !equalTo(b, []byte("purple")) {
+		t.Error("buffer changed", b.String())
+	}
+
+	// NULL and empty values leave a null buffer.
+	for _, src := range []interface{}{nil, []byte{}, ""} {
+		if err := b.Scan(src); err != nil {
+			t.Error("unexpected error:", err)
+		}
+		if b.IsAlive() || b.Size() != 0 {
+			t.Error("expected null buffer")
+		}
+	}
+	b.Destroy()
+
+	var n *LockedBuffer
+	if err := n.Scan([]byte("yellow")); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,48 @@
+package memguard
+
+import (
+	"sync/atomic"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// Number of bytes of stack below the caller that SanitizeStack overwrites.
+const stackWindow = 16 * 1024
+
+// Set to 1 if copying methods should sanitize the stack before returning.
+var sanitizeStack int32
+
+/*
+SanitizeStack overwrites a window of the current goroutine's stack below the calling function's frame with zeros. The frames of functions that have already returned lie in this region, and they may still hold fragments of a secret in spilled registers or temporary values, from where they could end up in a core dump. Calling SanitizeStack straight after a sensitive operation reduces this risk.
+
+It works by calling a function with a large zeroed frame, so only memory that the runtime considers free is overwritten and no live frame is affected. This is best-effort hardening that depends on how the Go compiler and runtime lay out stacks. In particular, the frames of functions that ran deeper than the window are left alone, as is memory left behind when a goroutine's stack has been moved by the runtime to grow or shrink it.
+*/
+func SanitizeStack() {
+	wipeStackWindow()
+}
+
+//go:noinline
+func wipeStackWindow() {
+	var window [stackWindow]byte
+	core.Wipe(window[:])
+}
+
+/*
+SetSanitizeStack enables or disables calling SanitizeStack at the end of every method that copies data into or out of a LockedBuffer: Copy, CopyAt, CopyFit, CopyTo, Move and MoveAt. This clears temporaries left on the stack by the copy, at the cost of overwriting 16 KiB of stack on every call.
+
+It is disabled by default.
+*/
+func SetSanitizeStack(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&sanitizeStack, v)
+}
+
+// Calls SanitizeStack if enabled with SetSanitizeStack. It must be deferred by the copying method itself.
+func sanitizeStackIfEnabled() {
+	if atomic.LoadInt32(&sanitizeStack) == 1 {
+		wipeStackWindow()
+	}
+}
@@ -0,0 +1,83 @@
+package memguard
+
+import (
+	"runtime/debug"
+	"testing"
+	"unsafe"
+)
+
+// Copies a secret into a temporary on the stack, leaving it behind after returning.
+//
+//go:noinline
+func leaveOnStack(secret []byte) byte {
+	var tmp [32]byte
+	for i := range tmp {
+		tmp[i] = secret[i]
+	}
+	var sum byte
+	for i := range tmp {
+		sum ^= tmp[i]
+	}
+	return sum
+}
+
+func TestSanitizeStack(t *testing.T) {
+	// Prevent the stack from being moved while it is being scanned.
+	defer debug.SetGCPercent(debug.SetGCPercent(-1))
+	growStack(64)
+
+	secret := NewBufferRandom(32)
+	defer secret.Destroy()
+	residueSource = make([]byte, 32)
+	secret.CopyTo(residueSource)
+	needle := residueSource
+
+	var marker byte
+	window := (*[stackWindow]byte)(unsafe.Pointer(uintptr(unsafe.Pointer(&marker)) - stackWindow))[:]
+
+	// Best-effort: the secret has to be found first for the result to mean anything. The scan makes no calls so as not to overwrite the region.
+	leaveOnStack(needle)
+	found := false
+	for i := 0; !found && i+len(needle) <= len(window); i++ {
+		j := 0
+		for j < len(needle) && window[i+j] == needle[j] {
+			j++
+		}
+		found = j == len(needle)
+	}
+	if !found {
+		t.Skip("secret was not left on the stack")
+	}
+
+	SanitizeStack()
+	found = false
+	for i := 0; !found && i+len(needle) <= len(window); i++ {
+		j := 0
+		for j < len(needle) && window[i+j] == needle[j] {
+			j++
+		}
+		found = j == len(needle)
+	}
+	if found {
+		t.Error("secret found on the stack after sanitizing")
+	}
+}
+
+func TestSetSanitizeStack(t *testing.T) {
+	SetSanitizeStack(true)
+	defer SetSanitizeStack(false)
+
+	// Copying methods work as usual.
+	b := NewBuffer(16)
+	defer b.Destroy()
+	b.Copy([]byte("yellow"))
+	b.CopyAt(6, []byte(" submarine"))
+	if !equalTo(b, []byte("yellow submarine")) {
+		t.Error("unexpected value", b.String())
+	}
+	b.Move([]byte("0123456789abcdef"))
+	out := make([]byte, 16)
+	if n, err := b.CopyTo(out); err != nil || n != 16 || string(out) != "0123456789abcdef" {
+		t.Error("unexpected result:", n, err, out)
+	}
+}
@@ -54,7 +54,7 @@ func TestStreamNextFlush(t *testing.T) {
 	if c.Size() != StreamChunkSize {
 		t.Error(c.Size())
 	}
-	if !c.EqualTo(ref[:StreamChunkSize]) {
+	if !equalTo(c, ref[:StreamChunkSize]) {
 		t.Error("incorrect data")
 	}
 	c.Destroy()
@@ -66,7 +66,7 @@ func TestStreamNextFlush(t *testing.T) {
 	if c.Size() != size-StreamChunkSize {
 		t.Error("unexpected length:", c.Size())
 	}
-	if !c.EqualTo(ref[StreamChunkSize:]) {
+	if !equalTo(c, ref[StreamChunkSize:]) {
 		t.Error("incorrect data")
 	}
 	c.Destroy()
@@ -149,7 +149,7 @@ func TestStreamingSanity(t *testing.T) {
 	if c.Size() != size {
 		t.Error("not enough data read back")
 	}
-	if !c.EqualTo(ref) {
+	if !equalTo(c, ref) {
 		t.Error("data mismatch")
 	}
 	c.Destroy()
@@ -169,7 +169,7 @@ func TestStreamingSanity(t *testing.T) {
 	if c.Size() != size {
 		t.Error("not enough data read back")
 	}
-	if !c.EqualTo(ref) {
+	if !equalTo(c, ref) {
 		t.Error("data mismatch")
 	}
 	c.Destroy()
@@ -191,7 +191,7 @@ func TestStreamingSanity(t *testing.T) {
 	if c.Size() != size-1 {
 		t.Error("not enough data read back:", c.Size(), "want", size-1)
 	}
-	if !c.EqualTo(make([]byte, size-1)) {
+	if !equalTo(c, make([]byte, size-1)) {
 		t.Error("data mismatch")
 	}
 	c.Destroy()
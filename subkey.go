@@ -0,0 +1,62 @@
+package memguard
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrInvalidSubkeyLength is returned when a subkey longer than HKDF can derive is requested.
+var ErrInvalidSubkeyLength = errors.New("<memguard::ErrInvalidSubkeyLength> subkey must be at most 8160 bytes")
+
+/*
+DeriveSubkey derives a subkey of a given length from a master key held in a LockedBuffer using HKDF-Expand with SHA-256, as defined in RFC 5869, with the label as the info parameter. The same master key and label always yield the same subkey, while different labels yield subkeys that are independent of each other, so a single master key can safely provide a separate key for every purpose of a protocol. The master key is used directly as the pseudorandom key, so it should be uniformly random and at least 32 bytes long; a key from a non-uniform source should first be passed through HMAC.
+
+The master key is read directly from guarded memory, every intermediate value is kept in guarded memory and wiped afterwards, and the subkey is returned inside a new immutable LockedBuffer.
+
+A length of less than one returns a null buffer and core.ErrNullBuffer, and ErrInvalidSubkeyLength is returned for a length greater than 8160 bytes. An error is also returned if the master key has been destroyed.
+*/
+func (master *LockedBuffer) DeriveSubkey(label string, length int) (*LockedBuffer, error) {
+	if length < 1 {
+		return newNullBuffer(), core.ErrNullBuffer
+	}
+	if length > 255*sha256.Size {
+		return newNullBuffer(), ErrInvalidSubkeyLength
+	}
+
+	// The message of each block, T(i-1) || info || i, followed by the space for T(i).
+	work := NewBuffer(2*sha256.Size + len(label) + 1)
+	defer work.Destroy()
+	prev, msg, next := work.Bytes()[:sha256.Size], work.Bytes()[:sha256.Size+len(label)+1], work.Bytes()[sha256.Size+len(label)+1:]
+	copy(msg[sha256.Size:], label)
+
+	subkey := NewBuffer(length)
+	out := subkey.Bytes()
+	if err := master.Access(false, func(prk []byte) error {
+		for i := 1; len(out) > 0; i++ {
+			msg[len(msg)-1] = byte(i)
+			m := msg
+			if i == 1 {
+				m = msg[sha256.Size:]
+			}
+			if _, err := core.HMAC(sha256.New, prk, m, next); err != nil {
+				return err
+			}
+			n := len(next)
+			if n > len(out) {
+				n = len(out)
+			}
+			core.Copy(out[:n], next)
+			out = out[n:]
+			core.Copy(prev, next)
+		}
+		return nil
+	}); err != nil {
+		subkey.Destroy()
+		return newNullBuffer(), err
+	}
+
+	subkey.Freeze()
+	return subkey, nil
+}
@@ -0,0 +1,76 @@
+package memguard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/hkdf"
+)
+
+func TestDeriveSubkey(t *testing.T) {
+	master := NewBufferRandom(32)
+	defer master.Destroy()
+
+	a, err := master.DeriveSubkey("encryption", 32)
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	defer a.Destroy()
+	if a.Size() != 32 || a.IsMutable() {
+		t.Error("invalid subkey")
+	}
+
+	// The same label is deterministic and different labels differ.
+	b, _ := master.DeriveSubkey("encryption", 32)
+	defer b.Destroy()
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("derivation is not deterministic")
+	}
+	for _, label := range []string{"authentication", "encryption2", ""} {
+		c, _ := master.DeriveSubkey(label, 32)
+		if bytes.Equal(a.Bytes(), c.Bytes()) {
+			t.Error("labels gave the same subkey:", label)
+		}
+		c.Destroy()
+	}
+
+	// Results match HKDF-Expand for lengths spanning several blocks.
+	for _, length := range []int{1, 31, 32, 33, 100, 255 * 32} {
+		c, err := master.DeriveSubkey("label", length)
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		expected := make([]byte, length)
+		io.ReadFull(hkdf.Expand(sha256.New, master.Bytes(), []byte("label")), expected)
+		if !equalTo(c, expected) {
+			t.Error("subkey does not match HKDF-Expand for length", length)
+		}
+		c.Destroy()
+	}
+
+	// RFC 5869 test case 1.
+	prk, _ := hex.DecodeString("077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	okm, _ := hex.DecodeString("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865")
+	k := NewBufferFromBytes(prk)
+	defer k.Destroy()
+	if c, err := k.DeriveSubkey(string(info), 42); err != nil || !equalTo(c, okm) {
+		t.Error("unexpected output for RFC 5869 test case;", err)
+	}
+
+	// Invalid arguments.
+	if c, err := master.DeriveSubkey("label", 0); err != core.ErrNullBuffer || c.IsAlive() {
+		t.Error("expected ErrNullBuffer; got", err)
+	}
+	if c, err := master.DeriveSubkey("label", 255*32+1); err != ErrInvalidSubkeyLength || c.IsAlive() {
+		t.Error("expected ErrInvalidSubkeyLength; got", err)
+	}
+	master.Destroy()
+	if c, err := master.DeriveSubkey("label", 32); err != core.ErrBufferExpired || c.IsAlive() {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,65 @@
+package memguard
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/awnumar/memguard/core"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ErrMismatch is returned when two entries of a secret that are expected to be identical differ.
+var ErrMismatch = errors.New("<memguard::ErrMismatch> entries do not match")
+
+// Where prompts are written. Standard error is used so that they are not mixed with the program's output.
+var promptWriter io.Writer = os.Stderr
+
+// Reads a secret from the terminal into an immutable LockedBuffer after displaying a prompt.
+var readTerminal = func(prompt string) (*LockedBuffer, error) {
+	io.WriteString(promptWriter, prompt)
+	defer io.WriteString(promptWriter, "\n")
+
+	data, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		core.Wipe(data)
+		return newNullBuffer(), err
+	}
+	return NewBufferFromBytes(data), nil
+}
+
+/*
+NewBufferFromTerminal displays a prompt on standard error and reads a line from the terminal attached to standard input into an immutable LockedBuffer, without echoing it. The line ending is not included.
+
+The terminal package returns the line in ordinary memory, from which it is moved into the LockedBuffer and wiped immediately. An error is returned if standard input is not a terminal. An empty entry returns a null buffer.
+*/
+func NewBufferFromTerminal(prompt string) (*LockedBuffer, error) {
+	return readTerminal(prompt)
+}
+
+/*
+NewBufferFromTerminalConfirmed is identical to NewBufferFromTerminal except that the secret is read twice, displaying prompt and then confirmPrompt, as is usual when a new password is being chosen. The two entries are compared in constant time.
+
+If they match, the second entry is destroyed and the first is returned. Otherwise both are destroyed and ErrMismatch is returned.
+*/
+func NewBufferFromTerminalConfirmed(prompt, confirmPrompt string) (*LockedBuffer, error) {
+	first, err := readTerminal(prompt)
+	if err != nil {
+		return first, err
+	}
+	second, err := readTerminal(confirmPrompt)
+	if err != nil {
+		first.Destroy()
+		return second, err
+	}
+	defer second.Destroy()
+
+	// Two empty entries are null buffers, which hold no data to compare.
+	if first.Size() != 0 || second.Size() != 0 {
+		if equal, _ := first.EqualTo(second.Bytes()); !equal {
+			first.Destroy()
+			return newNullBuffer(), ErrMismatch
+		}
+	}
+	return first, nil
+}
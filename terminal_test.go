@@ -0,0 +1,103 @@
+package memguard
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Replaces terminal input with a sequence of entries, returning the buffers that were handed out.
+func mockTerminal(t *testing.T, entries ...string) *[]*LockedBuffer {
+	var read []*LockedBuffer
+	original := readTerminal
+	t.Cleanup(func() { readTerminal = original })
+
+	readTerminal = func(prompt string) (*LockedBuffer, error) {
+		if len(entries) == 0 {
+			return newNullBuffer(), errors.New("no input")
+		}
+		b := NewBufferFromBytes([]byte(entries[0]))
+		entries = entries[1:]
+		read = append(read, b)
+		return b, nil
+	}
+	return &read
+}
+
+func TestNewBufferFromTerminal(t *testing.T) {
+	mockTerminal(t, "yellow submarine")
+	b, err := NewBufferFromTerminal("Password: ")
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("yellow submarine")) || b.IsMutable() {
+		t.Error("unexpected buffer", b.String())
+	}
+	b.Destroy()
+}
+
+func TestNewBufferFromTerminalConfirmed(t *testing.T) {
+	// Matching entries return the first and destroy the second.
+	read := mockTerminal(t, "yellow submarine", "yellow submarine")
+	b, err := NewBufferFromTerminalConfirmed("Password: ", "Confirm: ")
+	if err != nil {
+		t.Error("unexpected error:", err)
+	}
+	if !equalTo(b, []byte("yellow submarine")) {
+		t.Error("unexpected buffer", b.String())
+	}
+	if len(*read) != 2 || (*read)[0] != b || (*read)[1].IsAlive() {
+		t.Error("temporary entry not destroyed")
+	}
+	b.Destroy()
+
+	// Mismatching entries are both destroyed.
+	read = mockTerminal(t, "yellow submarine", "yellow submarinf")
+	b, err = NewBufferFromTerminalConfirmed("Password: ", "Confirm: ")
+	if err != ErrMismatch {
+		t.Error("expected ErrMismatch; got", err)
+	}
+	if b.IsAlive() {
+		t.Error("expected null buffer")
+	}
+	if len(*read) != 2 || (*read)[0].IsAlive() || (*read)[1].IsAlive() {
+		t.Error("entries not destroyed on mismatch")
+	}
+
+	// Entries of different lengths do not match.
+	mockTerminal(t, "yellow", "yellow submarine")
+	if _, err := NewBufferFromTerminalConfirmed("Password: ", "Confirm: "); err != ErrMismatch {
+		t.Error("expected ErrMismatch; got", err)
+	}
+
+	// A failure to read the confirmation destroys the first entry.
+	read = mockTerminal(t, "yellow submarine")
+	if b, err := NewBufferFromTerminalConfirmed("Password: ", "Confirm: "); err == nil || b.IsAlive() {
+		t.Error("expected error; got", err)
+	}
+	if len(*read) != 1 || (*read)[0].IsAlive() {
+		t.Error("first entry not destroyed")
+	}
+}
+
+func TestReadTerminal(t *testing.T) {
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		t.Skip("standard input is a terminal")
+	}
+
+	var prompts bytes.Buffer
+	original := promptWriter
+	promptWriter = &prompts
+	defer func() { promptWriter = original }()
+
+	b, err := readTerminal("Password: ")
+	if err == nil || b.IsAlive() {
+		t.Error("expected error reading from a non-terminal; got", err)
+	}
+	if prompts.String() != "Password: \n" {
+		t.Error("unexpected prompt output", prompts.String())
+	}
+}
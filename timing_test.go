@@ -0,0 +1,109 @@
+package memguard
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+The constant-time harness times a comparison against a guess that differs from the secret in its first byte, and against one that differs in its last byte. An early exit makes the first case markedly faster, so the ratio between the median timings of the two cases is checked against a threshold. Samples of the two cases are interleaved so that changes in system load affect both equally.
+*/
+const (
+	timingSize      = 4096 // Size of the compared values
+	timingSamples   = 101  // Number of timings taken for each case
+	timingBatch     = 64   // Number of comparisons per timing
+	timingThreshold = 2.0  // Largest allowed ratio between the cases
+)
+
+// Fails the test if the running time of compare depends on the position at which the guess first differs from the secret.
+func assertConstantTime(t *testing.T, name string, secret []byte, compare func(guess []byte)) {
+	if testing.Short() {
+		return
+	}
+	if ratio := timingRatio(secret, compare); ratio > timingThreshold {
+		t.Error(name, "running time depends on mismatch position; ratio", ratio)
+	}
+}
+
+// Fails the test if the running times of two operations differ.
+func assertSameTiming(t *testing.T, name string, x, y func()) {
+	if testing.Short() {
+		return
+	}
+	if ratio := compareTimings(x, y); ratio > timingThreshold {
+		t.Error(name, "running time depends on its inputs; ratio", ratio)
+	}
+}
+
+// Returns the ratio between the slower and the faster median timing of the first and last byte mismatch cases.
+func timingRatio(secret []byte, compare func(guess []byte)) float64 {
+	first := append([]byte(nil), secret...)
+	first[0] ^= 0xff
+	last := append([]byte(nil), secret...)
+	last[len(last)-1] ^= 0xff
+
+	return compareTimings(func() { compare(first) }, func() { compare(last) })
+}
+
+// Returns the ratio between the slower and the faster median timing of two operations.
+func compareTimings(x, y func()) float64 {
+	xs := make([]time.Duration, timingSamples)
+	ys := make([]time.Duration, timingSamples)
+	for i := 0; i < timingSamples; i++ {
+		xs[i] = timeBatch(x)
+		ys[i] = timeBatch(y)
+	}
+
+	a, b := float64(median(xs)), float64(median(ys))
+	if a > b {
+		return a / b
+	}
+	return b / a
+}
+
+func timeBatch(fn func()) time.Duration {
+	start := time.Now()
+	for i := 0; i < timingBatch; i++ {
+		fn()
+	}
+	return time.Since(start)
+}
+
+func median(d []time.Duration) time.Duration {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	return d[len(d)/2]
+}
+
+// Holds the results of timed comparisons so that they cannot be optimised away.
+var timingSink bool
+
+func TestTimingHarness(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping timing measurements in short mode")
+	}
+	if raceEnabled {
+		t.Skip("skipping timing measurements under the race detector, whose instrumentation hides the early exit")
+	}
+
+	// The harness should detect the early exit of a variable-time comparison.
+	secret := make([]byte, timingSize)
+	ScrambleBytes(secret)
+	if ratio := timingRatio(secret, func(guess []byte) { timingSink = bytes.Equal(secret, guess) }); ratio <= timingThreshold {
+		t.Error("harness did not detect variable-time comparison; ratio", ratio)
+	}
+}
+
+func TestConstantTimeComparisons(t *testing.T) {
+	b := NewBufferRandom(timingSize)
+	defer b.Destroy()
+	secret := make([]byte, timingSize)
+	b.CopyTo(secret)
+
+	assertConstantTime(t, "core.Equal", secret, func(guess []byte) { core.Equal(secret, guess) })
+	assertConstantTime(t, "EqualTo", secret, func(guess []byte) { equalTo(b, guess) })
+	assertConstantTime(t, "VerifyMAC", secret, func(guess []byte) { b.VerifyMAC(guess) })
+}
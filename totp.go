@@ -0,0 +1,129 @@
+package memguard
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"time"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrInvalidOTPParameters is returned when a one-time password is requested with an unsupported hash function, number of digits, period, or time.
+var ErrInvalidOTPParameters = errors.New("<memguard::ErrInvalidOTPParameters> one-time password parameters are invalid")
+
+// OTPOption is used to configure the generation of one-time passwords by HOTP and TOTP.
+type OTPOption func(*otpConfig)
+
+type otpConfig struct {
+	hash   crypto.Hash
+	period time.Duration
+}
+
+/*
+OTPHash selects the hash function used to compute one-time passwords. Supported values are crypto.SHA1 (the default), crypto.SHA256, and crypto.SHA512.
+*/
+func OTPHash(h crypto.Hash) OTPOption {
+	return func(c *otpConfig) {
+		c.hash = h
+	}
+}
+
+/*
+OTPPeriod sets the time step used by TOTP. It must be a positive whole number of seconds and defaults to 30 seconds.
+*/
+func OTPPeriod(d time.Duration) OTPOption {
+	return func(c *otpConfig) {
+		c.period = d
+	}
+}
+
+/*
+HOTP computes an RFC 4226 HMAC-based one-time password from a secret and a counter value. The password is returned as a string of decimal digits inside an immutable LockedBuffer.
+
+The HMAC is computed directly on the guarded secret with its intermediate values kept inside guarded memory, and the password never touches the unguarded heap. The number of digits must be between 6 and 10 inclusive.
+*/
+func HOTP(secret *LockedBuffer, counter uint64, digits int, opts ...OTPOption) (*LockedBuffer, error) {
+	c := otpConfig{hash: crypto.SHA1, period: 30 * time.Second}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return hotp(secret, counter, digits, c)
+}
+
+/*
+TOTP computes an RFC 6238 time-based one-time password from a secret at a given time. The password is returned as a string of decimal digits inside an immutable LockedBuffer.
+
+The counter is the number of whole periods elapsed between the Unix epoch and t. See HOTP for details on how the password is computed.
+*/
+func TOTP(secret *LockedBuffer, t time.Time, digits int, opts ...OTPOption) (*LockedBuffer, error) {
+	c := otpConfig{hash: crypto.SHA1, period: 30 * time.Second}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	// Validate the period and time step.
+	step := int64(c.period / time.Second)
+	if step < 1 || c.period%time.Second != 0 || t.Unix() < 0 {
+		return newNullBuffer(), ErrInvalidOTPParameters
+	}
+
+	return hotp(secret, uint64(t.Unix()/step), digits, c)
+}
+
+func hotp(secret *LockedBuffer, counter uint64, digits int, c otpConfig) (*LockedBuffer, error) {
+	// Select the hash function.
+	var h func() hash.Hash
+	switch c.hash {
+	case crypto.SHA1:
+		h = sha1.New
+	case crypto.SHA256:
+		h = sha256.New
+	case crypto.SHA512:
+		h = sha512.New
+	default:
+		return newNullBuffer(), ErrInvalidOTPParameters
+	}
+	if digits < 6 || digits > 10 {
+		return newNullBuffer(), ErrInvalidOTPParameters
+	}
+
+	// Check if the secret is still alive.
+	if !secret.IsAlive() {
+		return newNullBuffer(), core.ErrBufferExpired
+	}
+
+	// Allocate guarded memory for the HMAC tag.
+	tag := NewBuffer(h().Size())
+	defer tag.Destroy()
+
+	// Compute the HMAC over the big-endian counter.
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	if err := secret.Access(false, func(key []byte) error {
+		_, err := core.HMAC(h, key, msg[:], tag.Bytes())
+		return err
+	}); err != nil {
+		return newNullBuffer(), err
+	}
+
+	// Dynamic truncation.
+	t := tag.Bytes()
+	offset := t[len(t)-1] & 0x0f
+	code := uint64(binary.BigEndian.Uint32(t[offset:offset+4]) & 0x7fffffff)
+
+	// Write the decimal representation into a guarded buffer.
+	b := NewBuffer(digits)
+	for i := digits - 1; i >= 0; i-- {
+		b.Bytes()[i] = '0' + byte(code%10)
+		code /= 10
+	}
+	code = 0
+
+	b.Freeze()
+	return b, nil
+}
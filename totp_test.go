@@ -0,0 +1,116 @@
+package memguard
+
+import (
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 Appendix D test values.
+	known := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	secret := NewBufferFromBytes([]byte("12345678901234567890"))
+	for i, v := range known {
+		code, err := HOTP(secret, uint64(i), 6)
+		if err != nil {
+			t.Error(err)
+		}
+		if !equalTo(code, []byte(v)) {
+			t.Error("code doesn't match known value; counter", i, "got", code.String())
+		}
+		if code.IsMutable() {
+			t.Error("code buffer should be immutable")
+		}
+		code.Destroy()
+	}
+
+	// Invalid parameters.
+	if _, err := HOTP(secret, 0, 5); err != ErrInvalidOTPParameters {
+		t.Error("expected ErrInvalidOTPParameters; got", err)
+	}
+	if _, err := HOTP(secret, 0, 11); err != ErrInvalidOTPParameters {
+		t.Error("expected ErrInvalidOTPParameters; got", err)
+	}
+	if _, err := HOTP(secret, 0, 6, OTPHash(crypto.MD5)); err != ErrInvalidOTPParameters {
+		t.Error("expected ErrInvalidOTPParameters; got", err)
+	}
+
+	// Destroyed secret.
+	secret.Destroy()
+	code, err := HOTP(secret, 0, 6)
+	if err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if code.IsAlive() {
+		t.Error("expected destroyed buffer")
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 Appendix B test values.
+	sha1Secret := NewBufferFromBytes([]byte("12345678901234567890"))
+	sha256Secret := NewBufferFromBytes([]byte("12345678901234567890123456789012"))
+	sha512Secret := NewBufferFromBytes([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+	defer sha1Secret.Destroy()
+	defer sha256Secret.Destroy()
+	defer sha512Secret.Destroy()
+
+	known := []struct {
+		time   int64
+		sha1   string
+		sha256 string
+		sha512 string
+	}{
+		{59, "94287082", "46119246", "90693936"},
+		{1111111109, "07081804", "68084774", "25091201"},
+		{1111111111, "14050471", "67062674", "99943326"},
+		{1234567890, "89005924", "91819424", "93441116"},
+		{2000000000, "69279037", "90698825", "38618901"},
+		{20000000000, "65353130", "77737706", "47863826"},
+	}
+
+	for _, v := range known {
+		at := time.Unix(v.time, 0)
+
+		code, err := TOTP(sha1Secret, at, 8)
+		if err != nil {
+			t.Error(err)
+		}
+		if !equalTo(code, []byte(v.sha1)) {
+			t.Error("SHA1 code doesn't match known value at", v.time, "got", code.String())
+		}
+		code.Destroy()
+
+		code, err = TOTP(sha256Secret, at, 8, OTPHash(crypto.SHA256))
+		if err != nil {
+			t.Error(err)
+		}
+		if !equalTo(code, []byte(v.sha256)) {
+			t.Error("SHA256 code doesn't match known value at", v.time, "got", code.String())
+		}
+		code.Destroy()
+
+		code, err = TOTP(sha512Secret, at, 8, OTPHash(crypto.SHA512), OTPPeriod(30*time.Second))
+		if err != nil {
+			t.Error(err)
+		}
+		if !equalTo(code, []byte(v.sha512)) {
+			t.Error("SHA512 code doesn't match known value at", v.time, "got", code.String())
+		}
+		code.Destroy()
+	}
+
+	// Invalid periods and times.
+	if _, err := TOTP(sha1Secret, time.Unix(59, 0), 8, OTPPeriod(0)); err != ErrInvalidOTPParameters {
+		t.Error("expected ErrInvalidOTPParameters; got", err)
+	}
+	if _, err := TOTP(sha1Secret, time.Unix(59, 0), 8, OTPPeriod(1500*time.Millisecond)); err != ErrInvalidOTPParameters {
+		t.Error("expected ErrInvalidOTPParameters; got", err)
+	}
+	if _, err := TOTP(sha1Secret, time.Unix(-1, 0), 8); err != ErrInvalidOTPParameters {
+		t.Error("expected ErrInvalidOTPParameters; got", err)
+	}
+}
@@ -0,0 +1,56 @@
+package memguard
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/awnumar/memguard/core"
+)
+
+// ErrInvalidVarint is returned when the bytes at an offset do not hold a valid unsigned varint.
+var ErrInvalidVarint = errors.New("<memguard::ErrInvalidVarint> varint is truncated or overflows 64 bits")
+
+/*
+Uvarint decodes an unsigned variable-length integer, as used by encoding/binary and Protocol Buffers, directly from the data of a LockedBuffer at a given offset. It returns the value along with the number of bytes it occupied.
+
+core.ErrOutOfBounds is returned if the offset is out of range, and ErrInvalidVarint is returned if the varint runs past the end of the buffer or overflows a uint64. An error is also returned if the LockedBuffer has been destroyed.
+*/
+func (b *LockedBuffer) Uvarint(offset int) (uint64, int, error) {
+	var v uint64
+	var n int
+	err := b.Access(false, func(data []byte) error {
+		if offset < 0 || offset >= len(data) {
+			return core.ErrOutOfBounds
+		}
+		v, n = binary.Uvarint(data[offset:])
+		if n <= 0 {
+			v, n = 0, 0
+			return ErrInvalidVarint
+		}
+		return nil
+	})
+	return v, n, err
+}
+
+/*
+PutUvarint encodes an unsigned variable-length integer directly into the data of a LockedBuffer at a given offset, and returns the number of bytes written. Nothing is written unless the whole encoding fits.
+
+core.ErrOutOfBounds is returned if the encoding does not fit within the buffer at the offset. An error is also returned if the LockedBuffer is immutable or has been destroyed.
+*/
+func (b *LockedBuffer) PutUvarint(offset int, v uint64) (int, error) {
+	n := 1
+	for x := v; x >= 0x80; x >>= 7 {
+		n++
+	}
+	err := b.Access(true, func(data []byte) error {
+		if offset < 0 || offset > len(data)-n {
+			return core.ErrOutOfBounds
+		}
+		binary.PutUvarint(data[offset:], v)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
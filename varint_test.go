@@ -0,0 +1,81 @@
+package memguard
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestUvarint(t *testing.T) {
+	b := NewBuffer(32)
+	defer b.Destroy()
+
+	values := []uint64{0, 1, 127, 128, 300, 16383, 16384, 1 << 32, math.MaxUint64}
+	for _, offset := range []int{0, 5, 32 - binary.MaxVarintLen64} {
+		for _, v := range values {
+			n, err := b.PutUvarint(offset, v)
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			expected := make([]byte, binary.MaxVarintLen64)
+			expected = expected[:binary.PutUvarint(expected, v)]
+			if n != len(expected) || !equalTo(b, append(append(make([]byte, offset), expected...), make([]byte, 32-offset-n)...)) {
+				t.Error("incorrect encoding of", v, "at", offset, "; got", b.Bytes())
+			}
+
+			d, m, err := b.Uvarint(offset)
+			if err != nil {
+				t.Error("unexpected error:", err)
+			}
+			if d != v || m != n {
+				t.Error("round trip of", v, "at", offset, "gave", d, m)
+			}
+			b.Wipe()
+		}
+	}
+
+	// Encodings that do not fit are not written.
+	if _, err := b.PutUvarint(31, 128); err != core.ErrOutOfBounds {
+		t.Error("expected ErrOutOfBounds; got", err)
+	}
+	if !equalTo(b, make([]byte, 32)) {
+		t.Error("partial encoding written")
+	}
+	for _, offset := range []int{-1, 32} {
+		if _, err := b.PutUvarint(offset, 1); err != core.ErrOutOfBounds {
+			t.Error("expected ErrOutOfBounds; got", err)
+		}
+		if _, _, err := b.Uvarint(offset); err != core.ErrOutOfBounds {
+			t.Error("expected ErrOutOfBounds; got", err)
+		}
+	}
+
+	// Truncated and overflowing varints.
+	b.Copy(append(make([]byte, 31), 0x80))
+	if _, _, err := b.Uvarint(31); err != ErrInvalidVarint {
+		t.Error("expected ErrInvalidVarint; got", err)
+	}
+	overflow := make([]byte, 32)
+	for i := 0; i < 10; i++ {
+		overflow[i] = 0xff
+	}
+	b.Copy(overflow)
+	if _, _, err := b.Uvarint(0); err != ErrInvalidVarint {
+		t.Error("expected ErrInvalidVarint; got", err)
+	}
+
+	// Immutable and destroyed buffers.
+	b.Freeze()
+	if _, err := b.PutUvarint(0, 1); err != core.ErrBufferImmutable {
+		t.Error("expected ErrBufferImmutable; got", err)
+	}
+	b.Destroy()
+	if _, _, err := b.Uvarint(0); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+	if _, err := b.PutUvarint(0, 1); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}
@@ -0,0 +1,74 @@
+package memguard
+
+import (
+	"sync"
+
+	"github.com/awnumar/memguard/core"
+)
+
+/*
+View is a lightweight read-only handle onto the memory of a LockedBuffer. It aliases the parent's memory instead of copying it, which makes it cheap to hand the same secret to multiple readers.
+
+Closing a View only detaches it; the parent's memory is unaffected. A View becomes invalid once it is closed or once its parent is destroyed, after which it will no longer reference any memory.
+*/
+type View struct {
+	sync.RWMutex
+
+	parent *LockedBuffer
+	closed bool
+}
+
+/*
+NewView returns a new read-only View onto the memory of a LockedBuffer. An error is returned if the LockedBuffer has been destroyed.
+
+A live View holds a reference to its parent, so the parent will not be garbage collected until the View is closed.
+*/
+func (b *LockedBuffer) NewView() (*View, error) {
+	if !b.IsAlive() {
+		return nil, core.ErrBufferExpired
+	}
+	return &View{parent: b}, nil
+}
+
+/*
+Bytes returns a byte slice referencing the parent's protected region of memory. The slice must only be read from. If the View is invalid, a nil slice is returned.
+
+The slice should not be retained after the parent is destroyed.
+*/
+func (v *View) Bytes() []byte {
+	v.RLock()
+	defer v.RUnlock()
+
+	if v.closed {
+		return nil
+	}
+	return v.parent.Bytes()
+}
+
+/*
+Size returns the length of the memory referenced by the View. An invalid View has a size of zero.
+*/
+func (v *View) Size() int {
+	return len(v.Bytes())
+}
+
+/*
+Valid returns a boolean value indicating if the View can still be used, i.e. that it has not been closed and that its parent has not been destroyed.
+*/
+func (v *View) Valid() bool {
+	v.RLock()
+	defer v.RUnlock()
+
+	return !v.closed && v.parent.IsAlive()
+}
+
+/*
+Close detaches the View from its parent. The parent's memory is not modified. Calling Close more than once has no effect.
+*/
+func (v *View) Close() {
+	v.Lock()
+	defer v.Unlock()
+
+	v.closed = true
+	v.parent = nil
+}
@@ -0,0 +1,60 @@
+package memguard
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+
+	"github.com/awnumar/memguard/core"
+)
+
+func TestNewView(t *testing.T) {
+	b := NewBufferFromBytes([]byte("yellow submarine"))
+
+	v, err := b.NewView()
+	if err != nil {
+		t.Error(err)
+	}
+	w, err := b.NewView()
+	if err != nil {
+		t.Error(err)
+	}
+	if !v.Valid() || !w.Valid() {
+		t.Error("views should be valid")
+	}
+
+	// Views alias the parent's memory.
+	if !bytes.Equal(v.Bytes(), []byte("yellow submarine")) || v.Size() != 16 {
+		t.Error("view contents incorrect")
+	}
+	if unsafe.Pointer(&v.Bytes()[0]) != unsafe.Pointer(&b.Bytes()[0]) {
+		t.Error("view does not share memory with parent")
+	}
+	b.Melt()
+	b.Bytes()[0] = 'Y'
+	if w.Bytes()[0] != 'Y' {
+		t.Error("view does not reflect changes to parent")
+	}
+
+	// Closing a view does not affect the parent or other views.
+	v.Close()
+	v.Close()
+	if v.Valid() || v.Bytes() != nil || v.Size() != 0 {
+		t.Error("closed view should be invalid")
+	}
+	if !b.IsAlive() || !w.Valid() {
+		t.Error("closing a view affected the parent")
+	}
+	if !bytes.Equal(b.Bytes(), []byte("Yellow submarine")) {
+		t.Error("parent contents changed")
+	}
+
+	// Destroying the parent invalidates remaining views.
+	b.Destroy()
+	if w.Valid() || w.Bytes() != nil {
+		t.Error("view should be invalid after parent is destroyed")
+	}
+	if _, err := b.NewView(); err != core.ErrBufferExpired {
+		t.Error("expected ErrBufferExpired; got", err)
+	}
+}